@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+)
+
+// DictionaryAdd adds the "word" values of the form to the personal
+// dictionary, so that they are not corrected to other words
+func DictionaryAdd(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	words := r.Form["word"]
+	if len(words) == 0 {
+		http.Error(w, "no words given", http.StatusBadRequest)
+		return
+	}
+	if err := wordlist.AddPersonal(words...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,12 +1,17 @@
 package controllers
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/jimmykarily/open-ocr-reader/internal/export"
 	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
 	"github.com/jimmykarily/open-ocr-reader/internal/oor"
 	"github.com/jimmykarily/open-ocr-reader/internal/process"
@@ -14,32 +19,133 @@ import (
 	"github.com/pkg/errors"
 )
 
-func ImageUpload(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
-	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
-	}
+// ImageUpload reads the uploaded photo out loud. The OCR uses the clients of
+// the pool and the request fails with 503 when they are all busy. When the
+// "format" form field is "hocr" or "alto", the text with its layout is
+// returned in that format. When it is "pdf", a searchable PDF of the
+// processed pages is downloaded. The headers, the footers and the page
+// numbers are only read when the "speak-margins" form field is set.
+func ImageUpload(pool *ocr.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
 
-	tmpFile, status, err := ReceiveFile(w, r)
-	if err != nil {
-		http.Error(w, err.Error(), status)
-		return
-	}
-	defer os.Remove(tmpFile)
+		tmpFile, status, err := ReceiveFile(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		defer os.Remove(tmpFile)
 
-	parserDeps := oor.ParserDeps{
-		Processor: process.NewDefaultProcessor(),
-		OCR:       ocr.NewTesseractOCR(),
-		TTS:       tts.NewDefaultTTS(),
-	}
+		format := r.FormValue("format")
+		if format != "" && format != export.FormatHOCR && format != export.FormatALTO && format != "pdf" {
+			http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+			return
+		}
 
-	if err := oor.Parse(tmpFile, parserDeps); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+		// The part of the image the user selected, if any
+		selection, err := process.ParseSelection(r.FormValue("selection"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The languages of the text, e.g. "eng+ell". The server default is
+		// used when the request has none.
+		languages := ocr.ParseLanguages(r.FormValue("lang"))
+		if languages == nil {
+			languages = ocr.LanguagesFromEnv()
+		}
+
+		// A checked checkbox is sent as "on"
+		speakMargins := r.FormValue("speak-margins") == "on" || r.FormValue("speak-margins") == "true"
 
-	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusTemporaryRedirect)
+		stages, err := process.StagesFromEnv()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		processor, err := process.NewProcessorFromSpec(stages)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		variants, err := oor.DefaultVariants(stages, languages, pool)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		retryBelow, err := oor.RetryBelowFromEnv()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		corrector, err := ocr.NewCorrector()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		parserDeps := oor.ParserDeps{
+			Processor:    processor,
+			OCR:          ocr.TesseractOCR{Languages: languages, Pool: pool},
+			TTS:          tts.NewDefaultTTS(),
+			Selection:    selection,
+			Languages:    languages,
+			Pool:         pool,
+			SpeakMargins: speakMargins,
+			Variants:     variants,
+			RetryBelow:   retryBelow,
+			Corrector:    corrector,
+			DebugDir:     oor.DebugDirFromEnv(),
+		}
+
+		result, err := oor.Parse(tmpFile, parserDeps)
+		if err != nil {
+			if explanation, ok := process.Explain(err); ok {
+				http.Error(w, explanation, http.StatusUnprocessableEntity)
+				return
+			}
+			if errors.Is(err, ocr.ErrBusy) {
+				w.Header().Set("Retry-After", "10")
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		imageName := "upload.jpg"
+		if files := r.MultipartForm.File["image-file"]; len(files) > 0 {
+			imageName = files[0].Filename
+		}
+		switch format {
+		case "pdf":
+			var buf bytes.Buffer
+			if err := result.WritePDF(&buf); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			pdfName := strings.TrimSuffix(imageName, filepath.Ext(imageName)) + ".pdf"
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": pdfName}))
+			w.Write(buf.Bytes())
+			return
+		case export.FormatHOCR, export.FormatALTO:
+			w.Header().Set("Content-Type", export.ContentType(format))
+			if err := export.Write(w, format, result.Document(imageName)); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		http.Redirect(w, r, r.Header.Get("Referer"), http.StatusTemporaryRedirect)
+	}
 }
 
 func ReceiveFile(w http.ResponseWriter, r *http.Request) (string, int, error) {
@@ -0,0 +1,156 @@
+// Package debug is responsible for collecting the intermediate results of a
+// run (images, values and timings) and writing them as an HTML report.
+package debug
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Recorder collects everything that happens during a single run. A nil
+// Recorder is valid and records nothing, so callers don't need to check if
+// debugging is enabled.
+type Recorder struct {
+	baseDir string
+	started time.Time
+
+	mu      sync.Mutex
+	entries []entry
+}
+
+type entry struct {
+	// At is the time since the start of the run
+	At    time.Duration
+	Name  string
+	Text  string
+	Image template.URL
+}
+
+// New returns a Recorder that writes its report in a new directory inside
+// baseDir. It returns nil (a Recorder that records nothing) if baseDir is
+// empty.
+func New(baseDir string) *Recorder {
+	if baseDir == "" {
+		return nil
+	}
+
+	return &Recorder{baseDir: baseDir, started: time.Now()}
+}
+
+// Enabled returns true if the Recorder records anything. It can be used to
+// skip expensive work that is only needed for debugging.
+func (r *Recorder) Enabled() bool {
+	return r != nil
+}
+
+// Image records an encoded image (e.g. a PNG or a JPEG)
+func (r *Recorder) Image(name, mimeType string, data []byte) {
+	if r == nil {
+		return
+	}
+
+	url := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	r.add(entry{Name: name, Image: template.URL(url)})
+}
+
+// Notef records a value (e.g. an angle or the number of contours found)
+func (r *Recorder) Notef(name, format string, args ...any) {
+	if r == nil {
+		return
+	}
+
+	r.add(entry{Name: name, Text: fmt.Sprintf(format, args...)})
+}
+
+// Time records how long something took. It returns the function that stops
+// the clock, e.g.:
+//
+//	defer rec.Time("ocr")()
+func (r *Recorder) Time(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		r.add(entry{Name: name, Text: "took " + time.Since(start).String()})
+	}
+}
+
+func (r *Recorder) add(e entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e.At = time.Since(r.started)
+	r.entries = append(r.entries, e)
+}
+
+// Write writes a self contained HTML report in a new directory and returns
+// the path to the report
+func (r *Recorder) Write() (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(r.baseDir, 0755); err != nil {
+		return "", errors.Wrap(err, "creating the debug directory")
+	}
+	dir, err := os.MkdirTemp(r.baseDir, r.started.Format("20060102-150405-"))
+	if err != nil {
+		return "", errors.Wrap(err, "creating the report directory")
+	}
+
+	reportPath := filepath.Join(dir, "index.html")
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return "", errors.Wrap(err, "creating the report file")
+	}
+	defer f.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err = reportTemplate.Execute(f, struct {
+		Started time.Time
+		Total   time.Duration
+		Entries []entry
+	}{r.started, time.Since(r.started), r.entries})
+	if err != nil {
+		return "", errors.Wrap(err, "writing the report")
+	}
+
+	return reportPath, nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!doctype html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <title>open-ocr-reader run {{ .Started.Format "2006-01-02 15:04:05" }}</title>
+    <style>
+      body { font-family: sans-serif; }
+      td { vertical-align: top; padding: 4px 8px; border-bottom: 1px solid #ddd; }
+      img { max-width: 800px; border: 1px solid #888; }
+    </style>
+  </head>
+  <body>
+    <h1>Run {{ .Started.Format "2006-01-02 15:04:05" }}</h1>
+    <p>Total time: {{ .Total }}</p>
+    <table>
+      {{- range .Entries }}
+      <tr>
+        <td>{{ .At }}</td>
+        <td><b>{{ .Name }}</b></td>
+        <td>{{ if .Image }}<a href="{{ .Image }}"><img src="{{ .Image }}" alt="{{ .Name }}"></a>{{ else }}{{ .Text }}{{ end }}</td>
+      </tr>
+      {{- end }}
+    </table>
+  </body>
+</html>
+`))
@@ -0,0 +1,42 @@
+package debug_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/debug"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recorder", func() {
+	It("records nothing when disabled", func() {
+		rec := New("")
+		Expect(rec.Enabled()).To(BeFalse())
+		rec.Notef("deskew", "angle %d", 3)
+		rec.Time("ocr")()
+
+		path, err := rec.Write()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(BeEmpty())
+	})
+
+	It("writes every run in its own directory", func() {
+		baseDir := filepath.Join(GinkgoT().TempDir(), "not-yet-created")
+
+		first := New(baseDir)
+		first.Notef("deskew", "skew angle: %.1f", 2.5)
+		first.Image("threshold", "image/png", []byte("not really a png"))
+		firstPath, err := first.Write()
+		Expect(err).ToNot(HaveOccurred())
+
+		secondPath, err := New(baseDir).Write()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filepath.Dir(firstPath)).ToNot(Equal(filepath.Dir(secondPath)))
+
+		report, err := os.ReadFile(firstPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(report)).To(ContainSubstring("skew angle: 2.5"))
+		Expect(string(report)).To(ContainSubstring(`src="data:image/png;base64,bm90IHJlYWxseSBhIHBuZw=="`))
+	})
+})
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"image"
+	"io"
+	"strconv"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/version"
+)
+
+// ALTO writes the document as ALTO XML version 4:
+// https://www.loc.gov/standards/alto/. ALTO has no paragraphs, so every
+// paragraph is a TextBlock.
+func ALTO(w io.Writer, doc Document) error {
+	layout := el("Layout", nil)
+	for p, page := range doc.Pages {
+		layout.Children = append(layout.Children, altoPage(doc, p+1, page))
+	}
+
+	root := el("alto", []string{"xmlns", "http://www.loc.gov/standards/alto/ns-v4#"},
+		el("Description", nil,
+			text("MeasurementUnit", "pixel"),
+			el("sourceImageInformation", nil, text("fileName", doc.Image)),
+			el("OCRProcessing", []string{"ID", "OCR_0"},
+				el("ocrProcessingStep", nil,
+					el("processingSoftware", nil,
+						text("softwareName", "open-ocr-reader"),
+						text("softwareVersion", version.Version),
+					),
+				),
+			),
+		),
+		layout,
+	)
+
+	return write(w, "", root)
+}
+
+func altoPage(doc Document, number int, page Page) node {
+	attrs := []string{"ID", "page_" + itoa(number), "PHYSICAL_IMG_NR", itoa(number)}
+	if page.Number != "" {
+		attrs = append(attrs, "PRINTED_IMG_NR", page.Number)
+	}
+	attrs = append(attrs, "WIDTH", itoa(doc.Size.X), "HEIGHT", itoa(doc.Size.Y))
+	space := el("PrintSpace", position(page.Box))
+	lang := languageTag(page.Languages)
+
+	blocks, lines, words := 0, 0, 0
+	for _, b := range page.Blocks {
+		for _, p := range b.Paragraphs {
+			blocks++
+			blockAttrs := append([]string{"ID", id("block", number, blocks)}, position(p.Box)...)
+			if lang != "" {
+				blockAttrs = append(blockAttrs, "LANG", lang)
+			}
+			block := el("TextBlock", blockAttrs)
+			for _, l := range p.Lines {
+				lines++
+				line := el("TextLine", append([]string{"ID", id("line", number, lines)}, position(l.Box)...))
+				for j, word := range l.Words {
+					if j > 0 {
+						line.Children = append(line.Children, el("SP", nil))
+					}
+					words++
+					wordAttrs := append([]string{"ID", id("word", number, words), "CONTENT", word.Text}, position(word.Box)...)
+					wordAttrs = append(wordAttrs, "WC", strconv.FormatFloat(word.Confidence/100, 'f', 2, 64))
+					line.Children = append(line.Children, el("String", wordAttrs))
+				}
+				block.Children = append(block.Children, line)
+			}
+			space.Children = append(space.Children, block)
+		}
+	}
+
+	return el("Page", attrs, space)
+}
+
+// position returns the ALTO attributes of the position and the size of a box
+func position(r image.Rectangle) []string {
+	return []string{"HPOS", itoa(r.Min.X), "VPOS", itoa(r.Min.Y), "WIDTH", itoa(r.Dx()), "HEIGHT", itoa(r.Dy())}
+}
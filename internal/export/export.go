@@ -0,0 +1,140 @@
+// Package export is responsible for writing what was read from a photo in
+// the formats other OCR tools understand (hOCR and ALTO XML), so that the
+// pages can be archived and reused.
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/pkg/errors"
+)
+
+// The formats a Document can be written in
+const (
+	FormatHOCR = "hocr"
+	FormatALTO = "alto"
+)
+
+// Document is the text found in a photo with its layout, in the coordinates
+// of the photo
+type Document struct {
+	// Image is the file name of the photo
+	Image string
+	// Size is the width and the height of the photo
+	Size image.Point
+	// Pages are in reading order
+	Pages []Page
+}
+
+// Page is a page of the photo (e.g. one of the two pages of an open book)
+type Page struct {
+	// Box is the part of the photo with the page
+	Box image.Rectangle
+	// Number is the printed page number, if found
+	Number string
+	// Languages are the tesseract languages the text was read with
+	Languages []string
+	Blocks    []ocr.Block
+}
+
+// Write writes the document in the given format
+func Write(w io.Writer, format string, doc Document) error {
+	switch format {
+	case FormatHOCR:
+		return HOCR(w, doc)
+	case FormatALTO:
+		return ALTO(w, doc)
+	}
+
+	return errors.Errorf("unknown export format %q (known formats: hocr, alto)", format)
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	if format == FormatHOCR {
+		return "text/html; charset=utf-8"
+	}
+
+	return "application/xml; charset=utf-8"
+}
+
+// node is an element of the written XML. The attributes are kept in the
+// order they are given.
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []node
+}
+
+// el returns an element with the given name, attributes (pairs of names and
+// values) and children
+func el(name string, attrs []string, children ...node) node {
+	n := node{XMLName: xml.Name{Local: name}, Children: children}
+	for j := 0; j+1 < len(attrs); j += 2 {
+		n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: attrs[j]}, Value: attrs[j+1]})
+	}
+
+	return n
+}
+
+// text returns an element with text
+func text(name string, value string) node {
+	return node{XMLName: xml.Name{Local: name}, Text: value}
+}
+
+// write writes the XML declaration, the doctype (if any) and the indented
+// root element
+func write(w io.Writer, doctype string, root node) error {
+	if _, err := io.WriteString(w, xml.Header+doctype); err != nil {
+		return errors.Wrap(err, "writing the header")
+	}
+	data, err := xml.MarshalIndent(root, "", " ")
+	if err != nil {
+		return errors.Wrap(err, "encoding the document")
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "writing the document")
+	}
+
+	return nil
+}
+
+// languageTags are the BCP 47 tags (ISO 639-1 codes where there is one) of
+// the tesseract languages
+var languageTags = map[string]string{
+	"afr": "af", "ara": "ar", "bul": "bg", "cat": "ca", "ces": "cs",
+	"chi_sim": "zh-Hans", "chi_tra": "zh-Hant", "dan": "da", "deu": "de",
+	"ell": "el", "eng": "en", "est": "et", "fas": "fa", "fin": "fi",
+	"fra": "fr", "grc": "grc", "heb": "he", "hin": "hi", "hrv": "hr",
+	"hun": "hu", "ind": "id", "ita": "it", "jpn": "ja", "kor": "ko",
+	"lat": "la", "lav": "lv", "lit": "lt", "nld": "nl", "nor": "no",
+	"pol": "pl", "por": "pt", "ron": "ro", "rus": "ru", "slk": "sk",
+	"slv": "sl", "spa": "es", "srp": "sr", "srp_latn": "sr-Latn",
+	"swe": "sv", "tha": "th", "tur": "tr", "ukr": "uk", "vie": "vi",
+}
+
+// languageTag returns the BCP 47 tag of the language of the text. It returns
+// an empty string when the text is in more than one language, because the
+// formats allow only one, or the language is unknown.
+func languageTag(languages []string) string {
+	if len(languages) != 1 {
+		return ""
+	}
+
+	return languageTags[languages[0]]
+}
+
+// id returns an element id like "word_1_12"
+func id(kind string, page, n int) string {
+	return fmt.Sprintf("%s_%d_%d", kind, page, n)
+}
+
+func itoa(v int) string {
+	return strconv.Itoa(v)
+}
@@ -0,0 +1,74 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/export"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Export", func() {
+	var doc Document
+
+	BeforeEach(func() {
+		result := ocr.NewResult([]ocr.WordBox{
+			{Word: ocr.Word{Text: "Fish", Box: image.Rect(110, 220, 150, 240), Confidence: 91}, BlockNum: 1, ParNum: 1, LineNum: 1},
+			{Word: ocr.Word{Text: "&", Box: image.Rect(160, 220, 170, 240), Confidence: 62}, BlockNum: 1, ParNum: 1, LineNum: 1},
+			{Word: ocr.Word{Text: "chips", Box: image.Rect(110, 250, 160, 270), Confidence: 88}, BlockNum: 1, ParNum: 1, LineNum: 2},
+		})
+		doc = Document{
+			Image: "menu.jpg",
+			Size:  image.Pt(800, 600),
+			Pages: []Page{{Box: image.Rect(100, 200, 400, 500), Number: "12", Languages: []string{"eng"}, Blocks: result.Blocks}},
+		}
+	})
+
+	It("writes hOCR", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, FormatHOCR, doc)).To(Succeed())
+		out := buf.String()
+		Expect(out).To(ContainSubstring(`<!DOCTYPE html`))
+		Expect(out).To(ContainSubstring(`class="ocr_page" id="page_1" title="image &#34;menu.jpg&#34;; bbox 100 200 400 500; ppageno 0"`))
+		Expect(out).To(ContainSubstring(`<p class="ocr_par" id="par_1_1" lang="en" title="bbox 110 220 170 270">`))
+		Expect(out).To(ContainSubstring(`<span class="ocr_line" id="line_1_2" title="bbox 110 250 160 270">`))
+		Expect(out).To(ContainSubstring(`<span class="ocrx_word" id="word_1_2" title="bbox 160 220 170 240; x_wconf 62">&amp;</span>`))
+		Expect(xml.Unmarshal(buf.Bytes(), new(interface{}))).To(Succeed())
+	})
+
+	It("writes ALTO", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, FormatALTO, doc)).To(Succeed())
+		out := buf.String()
+		Expect(out).To(ContainSubstring(`<Page ID="page_1" PHYSICAL_IMG_NR="1" PRINTED_IMG_NR="12" WIDTH="800" HEIGHT="600">`))
+		Expect(out).To(ContainSubstring(`<PrintSpace HPOS="100" VPOS="200" WIDTH="300" HEIGHT="300">`))
+		Expect(out).To(ContainSubstring(`<TextBlock ID="block_1_1" HPOS="110" VPOS="220" WIDTH="60" HEIGHT="50" LANG="en">`))
+		Expect(out).To(ContainSubstring(`<String ID="word_1_1" CONTENT="Fish" HPOS="110" VPOS="220" WIDTH="40" HEIGHT="20" WC="0.91"></String>`))
+		Expect(out).To(ContainSubstring(`<SP></SP>`))
+		Expect(xml.Unmarshal(buf.Bytes(), new(interface{}))).To(Succeed())
+	})
+
+	It("leaves the language out when there is more than one", func() {
+		doc.Pages[0].Languages = []string{"eng", "ell"}
+		for _, format := range []string{FormatHOCR, FormatALTO} {
+			var buf bytes.Buffer
+			Expect(Write(&buf, format, doc)).To(Succeed())
+			Expect(buf.String()).ToNot(ContainSubstring(`lang=`))
+			Expect(buf.String()).ToNot(ContainSubstring(`LANG=`))
+		}
+	})
+
+	It("writes the languages as BCP 47 tags", func() {
+		doc.Pages[0].Languages = []string{"chi_sim"}
+		var buf bytes.Buffer
+		Expect(Write(&buf, FormatHOCR, doc)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`lang="zh-Hans"`))
+	})
+
+	It("fails for unknown formats", func() {
+		Expect(Write(&bytes.Buffer{}, "pdf", doc)).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/version"
+)
+
+const hocrDoctype = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">` + "\n"
+
+// HOCR writes the document as hOCR, the HTML based format of tesseract:
+// http://kba.github.io/hocr-spec/1.2/
+func HOCR(w io.Writer, doc Document) error {
+	body := el("body", nil)
+	for p, page := range doc.Pages {
+		body.Children = append(body.Children, hocrPage(doc, p+1, page))
+	}
+
+	root := el("html", []string{"xmlns", "http://www.w3.org/1999/xhtml"},
+		el("head", nil,
+			text("title", doc.Image),
+			el("meta", []string{"http-equiv", "Content-Type", "content", "text/html;charset=utf-8"}),
+			el("meta", []string{"name", "ocr-system", "content", "open-ocr-reader " + version.Version}),
+			el("meta", []string{"name", "ocr-capabilities", "content", "ocr_page ocr_carea ocr_par ocr_line ocrx_word ocrp_lang ocrp_wconf"}),
+		),
+		body,
+	)
+
+	return write(w, hocrDoctype, root)
+}
+
+func hocrPage(doc Document, number int, page Page) node {
+	title := fmt.Sprintf("image %q; %s; ppageno %d", doc.Image, bbox(page.Box), number-1)
+	div := el("div", []string{"class", "ocr_page", "id", fmt.Sprintf("page_%d", number), "title", title})
+	lang := languageTag(page.Languages)
+
+	blocks, pars, lines, words := 0, 0, 0, 0
+	for _, b := range page.Blocks {
+		blocks++
+		block := el("div", []string{"class", "ocr_carea", "id", id("block", number, blocks), "title", bbox(b.Box)})
+		for _, p := range b.Paragraphs {
+			pars++
+			parAttrs := []string{"class", "ocr_par", "id", id("par", number, pars)}
+			if lang != "" {
+				parAttrs = append(parAttrs, "lang", lang)
+			}
+			par := el("p", append(parAttrs, "title", bbox(p.Box)))
+			for _, l := range p.Lines {
+				lines++
+				line := el("span", []string{"class", "ocr_line", "id", id("line", number, lines), "title", bbox(l.Box)})
+				for _, word := range l.Words {
+					words++
+					title := fmt.Sprintf("%s; x_wconf %.0f", bbox(word.Box), word.Confidence)
+					span := el("span", []string{"class", "ocrx_word", "id", id("word", number, words), "title", title})
+					span.Text = word.Text
+					line.Children = append(line.Children, span)
+				}
+				par.Children = append(par.Children, line)
+			}
+			block.Children = append(block.Children, par)
+		}
+		div.Children = append(div.Children, block)
+	}
+
+	return div
+}
+
+func bbox(r image.Rectangle) string {
+	return fmt.Sprintf("bbox %d %d %d %d", r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
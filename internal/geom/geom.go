@@ -0,0 +1,109 @@
+// Package geom is responsible for mapping points between the coordinates of
+// the photo and the coordinates of the images the processor makes from it
+// (rotated, cropped, scaled, straightened).
+package geom
+
+import (
+	"image"
+	"math"
+)
+
+// Transform is a projective transformation of the plane, a 3x3 matrix in
+// row-major order. Points are mapped as (x, y, 1) column vectors.
+type Transform [9]float64
+
+// Identity returns the Transform that maps every point to itself
+func Identity() Transform {
+	return Transform{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// Translation returns the Transform that moves the points by dx and dy
+func Translation(dx, dy float64) Transform {
+	return Transform{1, 0, dx, 0, 1, dy, 0, 0, 1}
+}
+
+// Scaling returns the Transform that scales the points by sx and sy
+func Scaling(sx, sy float64) Transform {
+	return Transform{sx, 0, 0, 0, sy, 0, 0, 0, 1}
+}
+
+// Affine returns the Transform of a 2x3 affine matrix in row-major order, like
+// the ones OpenCV uses for rotations
+func Affine(m [6]float64) Transform {
+	return Transform{m[0], m[1], m[2], m[3], m[4], m[5], 0, 0, 1}
+}
+
+// RotationClockwise returns the Transform of rotating an image of the given
+// size by 90, 180 or 270 degrees clockwise. The rotated image starts at the
+// origin too. Other angles return the Identity.
+func RotationClockwise(degrees int, size image.Point) Transform {
+	w, h := float64(size.X), float64(size.Y)
+	switch degrees {
+	case 90:
+		return Transform{0, -1, h, 1, 0, 0, 0, 0, 1}
+	case 180:
+		return Transform{-1, 0, w, 0, -1, h, 0, 0, 1}
+	case 270:
+		return Transform{0, 1, 0, -1, 0, w, 0, 0, 1}
+	}
+
+	return Identity()
+}
+
+// Then returns the Transform that applies t and then u
+func (t Transform) Then(u Transform) Transform {
+	r := Transform{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[3*i+j] += u[3*i+k] * t[3*k+j]
+			}
+		}
+	}
+
+	return r
+}
+
+// Invert returns the Transform that undoes t. It returns false if t can't be
+// undone (e.g. it scales by 0).
+func (t Transform) Invert() (Transform, bool) {
+	a, b, c, d, e, f, g, h, i := t[0], t[1], t[2], t[3], t[4], t[5], t[6], t[7], t[8]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if math.Abs(det) < 1e-12 {
+		return Transform{}, false
+	}
+
+	return Transform{
+		(e*i - f*h) / det, (c*h - b*i) / det, (b*f - c*e) / det,
+		(f*g - d*i) / det, (a*i - c*g) / det, (c*d - a*f) / det,
+		(d*h - e*g) / det, (b*g - a*h) / det, (a*e - b*d) / det,
+	}, true
+}
+
+// Apply maps a point
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	w := t[6]*x + t[7]*y + t[8]
+	if w == 0 {
+		w = 1
+	}
+
+	return (t[0]*x + t[1]*y + t[2]) / w, (t[3]*x + t[4]*y + t[5]) / w
+}
+
+// Rect maps the corners of a rectangle and returns the smallest rectangle
+// that contains them
+func (t Transform) Rect(r image.Rectangle) image.Rectangle {
+	if r.Empty() {
+		return r
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range []image.Point{r.Min, {X: r.Max.X, Y: r.Min.Y}, r.Max, {X: r.Min.X, Y: r.Max.Y}} {
+		x, y := t.Apply(float64(p.X), float64(p.Y))
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+
+	return image.Rect(int(math.Floor(minX+1e-6)), int(math.Floor(minY+1e-6)), int(math.Ceil(maxX-1e-6)), int(math.Ceil(maxY-1e-6)))
+}
@@ -0,0 +1,44 @@
+package geom_test
+
+import (
+	"image"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/geom"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transform", func() {
+	It("applies transforms in order", func() {
+		t := Translation(10, 0).Then(Scaling(2, 3))
+		x, y := t.Apply(1, 1)
+		Expect(x).To(BeNumerically("~", 22))
+		Expect(y).To(BeNumerically("~", 3))
+	})
+
+	It("undoes transforms", func() {
+		t := Affine([6]float64{0.8, 0.6, 5, -0.6, 0.8, 7}).Then(Scaling(2, 2))
+		inverse, ok := t.Invert()
+		Expect(ok).To(BeTrue())
+		x, y := t.Then(inverse).Apply(12, 34)
+		Expect(x).To(BeNumerically("~", 12, 1e-9))
+		Expect(y).To(BeNumerically("~", 34, 1e-9))
+
+		_, ok = Scaling(0, 1).Invert()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rotates images by right angles", func() {
+		size := image.Pt(100, 50)
+		r := image.Rect(10, 5, 30, 15)
+		Expect(RotationClockwise(90, size).Rect(r)).To(Equal(image.Rect(35, 10, 45, 30)))
+		Expect(RotationClockwise(180, size).Rect(r)).To(Equal(image.Rect(70, 35, 90, 45)))
+		Expect(RotationClockwise(270, size).Rect(r)).To(Equal(image.Rect(5, 70, 15, 90)))
+		Expect(RotationClockwise(90, size).Then(RotationClockwise(270, image.Pt(50, 100))).Rect(r)).To(Equal(r))
+	})
+
+	It("returns the bounding box of mapped rectangles", func() {
+		Expect(Identity().Rect(image.Rect(1, 2, 3, 4))).To(Equal(image.Rect(1, 2, 3, 4)))
+		Expect(Scaling(0.5, 0.5).Rect(image.Rect(1, 2, 3, 4))).To(Equal(image.Rect(0, 1, 2, 2)))
+	})
+})
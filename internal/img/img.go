@@ -32,6 +32,19 @@ func New(imgPath string) (*Image, error) {
 	return &Image{Object: imgObject}, nil
 }
 
+// Crop returns the part of the image inside the given rectangle. The
+// returned image shares the pixels with the original one.
+func (i Image) Crop(r image.Rectangle) *Image {
+	sub, ok := i.Object.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return &i
+	}
+
+	return &Image{Object: sub.SubImage(r)}
+}
+
 // StoreTmp can be used to store the image to a temporary location.
 // Some libraries only work with file paths, not Image objects.
 // It's the callers responsibility to delete the temporary file.
@@ -0,0 +1,75 @@
+package img_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"gocv.io/x/gocv"
+)
+
+// The benchmarks below compare the old way of passing images between the
+// processor and the OCR (through JPEG files on disk) with the in-memory one.
+// Run them with:
+//
+//	go test -bench . -benchmem ./internal/img/
+
+func loadPhoto(b *testing.B) *img.Image {
+	image, err := img.New("../../assets/photo.jpg")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return image
+}
+
+func BenchmarkToMatThroughDisk(b *testing.B) {
+	image := loadPhoto(b)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		path, err := image.StoreTmp()
+		if err != nil {
+			b.Fatal(err)
+		}
+		mat := gocv.IMRead(path, gocv.IMReadColor)
+		mat.Close()
+		os.Remove(path)
+	}
+}
+
+func BenchmarkToMat(b *testing.B) {
+	image := loadPhoto(b)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		mat, err := image.ToMat()
+		if err != nil {
+			b.Fatal(err)
+		}
+		mat.Close()
+	}
+}
+
+func BenchmarkEncodeForOCRThroughDisk(b *testing.B) {
+	image := loadPhoto(b)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		path, err := image.StoreTmp()
+		if err != nil {
+			b.Fatal(err)
+		}
+		os.Remove(path)
+	}
+}
+
+func BenchmarkEncodeLossless(b *testing.B) {
+	image := loadPhoto(b)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := image.EncodeLossless(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
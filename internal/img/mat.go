@@ -0,0 +1,99 @@
+package img
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// FromMat returns an Image with a copy of the pixels of the Mat
+func FromMat(m gocv.Mat) (*Image, error) {
+	object, err := m.ToImage()
+	if err != nil {
+		return nil, errors.Wrap(err, "converting Mat to image")
+	}
+
+	return &Image{Object: object}, nil
+}
+
+// ToMat returns a Mat with a copy of the pixels of the image. Grayscale
+// images become single channel Mats, everything else becomes a BGR Mat (the
+// channel order OpenCV expects). No encoding is involved so nothing is lost.
+// It's the callers responsibility to close the Mat.
+func (i Image) ToMat() (gocv.Mat, error) {
+	if gray, ok := i.Object.(*image.Gray); ok {
+		grayMat, err := gocv.NewMatFromBytes(gray.Bounds().Dy(), gray.Bounds().Dx(), gocv.MatTypeCV8UC1, grayPixels(gray))
+		if err != nil {
+			return gocv.NewMat(), errors.Wrap(err, "creating the Mat")
+		}
+		defer grayMat.Close()
+
+		// The Mat points to Go memory that the garbage collector may free
+		// while regions of it are still used. The clone belongs to OpenCV.
+		return grayMat.Clone(), nil
+	}
+
+	rgba := toRGBA(i.Object)
+	rgbaMat, err := gocv.NewMatFromBytes(rgba.Bounds().Dy(), rgba.Bounds().Dx(), gocv.MatTypeCV8UC4, rgba.Pix)
+	if err != nil {
+		return gocv.NewMat(), errors.Wrap(err, "creating the Mat")
+	}
+	defer rgbaMat.Close()
+
+	result := gocv.NewMat()
+	gocv.CvtColor(rgbaMat, &result, gocv.ColorRGBAToBGR)
+
+	return result, nil
+}
+
+// EncodeLossless encodes the image as PNM (PGM for grayscale images, PPM for
+// everything else). It's a lossless format that is much faster to write than
+// PNG and that tesseract (leptonica) can read from memory.
+// http://netpbm.sourceforge.net/doc/pnm.html
+func (i Image) EncodeLossless() ([]byte, error) {
+	bounds := i.Object.Bounds()
+	buf := &bytes.Buffer{}
+
+	if gray, ok := i.Object.(*image.Gray); ok {
+		fmt.Fprintf(buf, "P5\n%d %d\n255\n", bounds.Dx(), bounds.Dy())
+		buf.Write(grayPixels(gray))
+		return buf.Bytes(), nil
+	}
+
+	rgba := toRGBA(i.Object)
+	fmt.Fprintf(buf, "P6\n%d %d\n255\n", bounds.Dx(), bounds.Dy())
+	buf.Grow(bounds.Dx() * bounds.Dy() * 3)
+	for j := 0; j < len(rgba.Pix); j += 4 {
+		buf.Write(rgba.Pix[j : j+3])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// grayPixels returns the pixels of the image without any padding between
+// the rows (which sub images have)
+func grayPixels(gray *image.Gray) []byte {
+	bounds := gray.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		offset := gray.PixOffset(bounds.Min.X, y)
+		pixels = append(pixels, gray.Pix[offset:offset+bounds.Dx()]...)
+	}
+
+	return pixels
+}
+
+// toRGBA returns a copy of the image as RGBA with no padding between the
+// rows. The image/draw package has fast paths for the common image types
+// (e.g. the YCbCr of decoded JPEG files).
+func toRGBA(object image.Image) *image.RGBA {
+	bounds := object.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), object, bounds.Min, draw.Src)
+
+	return rgba
+}
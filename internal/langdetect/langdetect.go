@@ -0,0 +1,169 @@
+// Package langdetect is responsible for guessing the language of a text. It
+// compares the character n-grams of the text with the n-grams of a sample
+// text of each language (Cavnar and Trenkle, "N-Gram-Based Text
+// Categorization"). The languages are named with their tesseract codes
+// (e.g. "eng", "deu").
+package langdetect
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// profileSize is the number of the most frequent n-grams kept per language
+const profileSize = 300
+
+//go:embed samples/*.txt
+var samples embed.FS
+
+var profiles = map[string]map[string]int{}
+
+func init() {
+	entries, err := samples.ReadDir("samples")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := samples.ReadFile(path.Join("samples", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		profiles[strings.TrimSuffix(e.Name(), ".txt")] = profile(string(data))
+	}
+}
+
+// scriptLanguages are the languages written in each script, as named by the
+// orientation and script detection of tesseract. The most common language
+// comes first.
+var scriptLanguages = map[string][]string{
+	"Latin":      {"eng", "spa", "fra", "deu", "por", "ita", "nld"},
+	"Greek":      {"ell"},
+	"Cyrillic":   {"rus", "ukr", "bul", "srp"},
+	"Arabic":     {"ara", "fas"},
+	"Hebrew":     {"heb"},
+	"Han":        {"chi_sim", "chi_tra"},
+	"Japanese":   {"jpn"},
+	"Katakana":   {"jpn"},
+	"Hiragana":   {"jpn"},
+	"Hangul":     {"kor"},
+	"Devanagari": {"hin", "mar", "nep"},
+	"Bengali":    {"ben"},
+	"Thai":       {"tha"},
+	"Armenian":   {"hye"},
+	"Georgian":   {"kat"},
+}
+
+// Guess is a language and how far the text is from it. Smaller distances
+// are better.
+type Guess struct {
+	Language string
+	Distance int
+}
+
+// Languages returns the languages that can be detected, sorted
+func Languages() []string {
+	result := []string{}
+	for lang := range profiles {
+		result = append(result, lang)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// ForScript returns the languages written in the given script, most common
+// first, or nil if the script is unknown
+func ForScript(script string) []string {
+	return scriptLanguages[script]
+}
+
+// Detect returns the candidate languages ordered by how likely it is that
+// the text is written in them, best first. All the known languages are
+// candidates if none are given. Unknown candidates are ignored.
+func Detect(text string, candidates ...string) []Guess {
+	if len(candidates) == 0 {
+		candidates = Languages()
+	}
+
+	p := profile(text)
+	guesses := []Guess{}
+	for _, lang := range candidates {
+		langProfile, ok := profiles[lang]
+		if !ok {
+			continue
+		}
+		guesses = append(guesses, Guess{Language: lang, Distance: distance(p, langProfile)})
+	}
+	sort.SliceStable(guesses, func(i, j int) bool {
+		return guesses[i].Distance < guesses[j].Distance
+	})
+
+	return guesses
+}
+
+// profile returns the rank of the most frequent 1 to 3 character n-grams
+// of the text. Words are padded with spaces so that the n-grams at the
+// beginning and the end of the words are counted separately.
+func profile(text string) map[string]int {
+	counts := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		runes := []rune(" " + w + " ")
+		for n := 1; n <= 3; n++ {
+			for j := 0; j+n <= len(runes); j++ {
+				gram := string(runes[j : j+n])
+				if gram == " " {
+					continue
+				}
+				counts[gram]++
+			}
+		}
+	}
+
+	grams := []string{}
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	ranks := map[string]int{}
+	for rank, g := range grams {
+		ranks[g] = rank
+	}
+
+	return ranks
+}
+
+// distance is the "out of place" measure: the sum of the differences of
+// the ranks of the n-grams in the two profiles. N-grams missing from the
+// language get the maximum penalty.
+func distance(text, lang map[string]int) int {
+	d := 0
+	for g, rank := range text {
+		langRank, ok := lang[g]
+		if !ok {
+			d += profileSize
+			continue
+		}
+		if rank > langRank {
+			d += rank - langRank
+		} else {
+			d += langRank - rank
+		}
+	}
+
+	return d
+}
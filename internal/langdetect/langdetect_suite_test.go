@@ -0,0 +1,13 @@
+package langdetect_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLangdetect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Langdetect Suite")
+}
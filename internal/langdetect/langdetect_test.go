@@ -0,0 +1,37 @@
+package langdetect_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/langdetect"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Detect", func() {
+	DescribeTable("detects the language of a paragraph",
+		func(text, lang string) {
+			guesses := Detect(text)
+			Expect(guesses).ToNot(BeEmpty())
+			Expect(guesses[0].Language).To(Equal(lang))
+		},
+		Entry("English", "The storm came in the middle of the night and the boats were still out at sea. Nobody in the village could sleep.", "eng"),
+		Entry("French", "La tempête est arrivée au milieu de la nuit et les bateaux étaient encore en mer. Personne dans le village ne pouvait dormir.", "fra"),
+		Entry("German", "Der Sturm kam mitten in der Nacht und die Boote waren noch auf dem Meer. Niemand im Dorf konnte schlafen.", "deu"),
+		Entry("Spanish", "La tormenta llegó en medio de la noche y los barcos todavía estaban en el mar. Nadie en el pueblo podía dormir.", "spa"),
+		Entry("Italian", "La tempesta arrivò nel mezzo della notte e le barche erano ancora in mare. Nessuno nel villaggio riusciva a dormire.", "ita"),
+		Entry("Portuguese", "A tempestade chegou no meio da noite e os barcos ainda estavam no mar. Ninguém na aldeia conseguia dormir.", "por"),
+		Entry("Dutch", "De storm kwam midden in de nacht en de boten waren nog op zee. Niemand in het dorp kon slapen.", "nld"),
+	)
+
+	It("only considers the candidates", func() {
+		guesses := Detect("The storm came in the middle of the night.", "fra", "deu", "xxx")
+		Expect(guesses).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("ForScript", func() {
+	It("returns the languages of the script", func() {
+		Expect(ForScript("Greek")).To(Equal([]string{"ell"}))
+		Expect(ForScript("Latin")[0]).To(Equal("eng"))
+		Expect(ForScript("Klingon")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,321 @@
+// Package layout is responsible for finding the blocks of text on a page and
+// the order in which they should be read.
+package layout
+
+import (
+	"image"
+)
+
+// Kind is the type of content of a region
+type Kind string
+
+const (
+	KindText Kind = "text"
+	// KindFigure is a photo, a diagram or any other content that isn't text
+	KindFigure Kind = "figure"
+	// KindCaption is the text that describes a figure
+	KindCaption Kind = "caption"
+	// KindHeader and KindFooter are the running heads at the top and the
+	// bottom of the page (e.g. the title of the book or the chapter)
+	KindHeader Kind = "header"
+	KindFooter Kind = "footer"
+	// KindPageNumber is the printed page number
+	KindPageNumber Kind = "page-number"
+)
+
+// Region is a part of a page
+type Region struct {
+	Rect image.Rectangle
+	Kind Kind
+}
+
+// Options tune the segmentation
+type Options struct {
+	// MinColumnGap is the minimum width of an empty vertical stripe for it
+	// to be considered the gap between two columns
+	MinColumnGap int
+	// MinBlockGap is the minimum height of an empty horizontal stripe for it
+	// to be considered the gap between two blocks of text
+	MinBlockGap int
+	// MinWidth and MinHeight are the minimum size of a region. Smaller
+	// regions are considered noise and dropped.
+	MinWidth  int
+	MinHeight int
+	// RightToLeft reads columns from right to left
+	RightToLeft bool
+}
+
+// Segment splits the page in blocks of text using the recursive XY-cut
+// algorithm. The mask should be white where there is text and black
+// everywhere else (the text is usually dilated so that characters, words and
+// lines form solid blocks).
+// The page is recursively split at the widest empty horizontal or vertical
+// stripe until no more stripes are found. Horizontal cuts are read top to
+// bottom and vertical cuts (columns) left to right, which gives the regions
+// in reading order.
+// https://en.wikipedia.org/wiki/Recursive_XY-cut
+func Segment(mask *image.Gray, opts Options) []Region {
+	regions := []Region{}
+	for _, r := range xyCut(mask, mask.Bounds(), opts) {
+		regions = append(regions, Region{Rect: r, Kind: KindText})
+	}
+
+	return regions
+}
+
+// MarkCaptions marks the text regions that describe a figure as captions. A
+// caption is a short text region (at most maxHeight tall) that overlaps a
+// figure horizontally and is at most maxGap away from it. The region right
+// below the figure is preferred over the one right above it.
+func MarkCaptions(regions []Region, maxGap, maxHeight int) {
+	for _, figure := range regions {
+		if figure.Kind != KindFigure {
+			continue
+		}
+
+		below, above := -1, -1
+		for j, r := range regions {
+			if r.Kind != KindText || r.Rect.Dy() > maxHeight {
+				continue
+			}
+			if r.Rect.Max.X <= figure.Rect.Min.X || r.Rect.Min.X >= figure.Rect.Max.X {
+				continue
+			}
+			if gap := r.Rect.Min.Y - figure.Rect.Max.Y; gap >= 0 && gap <= maxGap {
+				if below < 0 || r.Rect.Min.Y < regions[below].Rect.Min.Y {
+					below = j
+				}
+			}
+			if gap := figure.Rect.Min.Y - r.Rect.Max.Y; gap >= 0 && gap <= maxGap {
+				if above < 0 || r.Rect.Max.Y > regions[above].Rect.Max.Y {
+					above = j
+				}
+			}
+		}
+
+		if below >= 0 {
+			regions[below].Kind = KindCaption
+		} else if above >= 0 {
+			regions[above].Kind = KindCaption
+		}
+	}
+}
+
+// MarkMargins marks the text lines at the top and the bottom of the page as
+// header, footer or page number. A line is a text region at most maxHeight
+// tall. The top line is a header if the rest of the text starts at least
+// minGap below it and the bottom line is a footer if the rest of the text ends
+// at least minGap above it. Regions of a header or footer that are at most
+// maxNumberWidth wide are page numbers. A page with a single line of text has
+// no margins.
+func MarkMargins(regions []Region, maxHeight, minGap, maxNumberWidth int) {
+	markMargin(regions, KindHeader, maxHeight, minGap, maxNumberWidth)
+	markMargin(regions, KindFooter, maxHeight, minGap, maxNumberWidth)
+}
+
+// Margins returns the headers, the footers and the page numbers of the
+// regions, with a single text region for everything between them, in reading
+// order. The body is read as one block, like a page without regions. It
+// returns nil if no region is a margin.
+func Margins(regions []Region, page image.Rectangle) []Region {
+	headers, footers := []Region{}, []Region{}
+	top, bottom := page.Min.Y, page.Max.Y
+	for _, r := range regions {
+		if r.Kind != KindHeader && r.Kind != KindFooter && r.Kind != KindPageNumber {
+			continue
+		}
+		// Page numbers can be at the top or at the bottom of the page
+		if r.Rect.Max.Y <= (page.Min.Y+page.Max.Y)/2 {
+			headers = append(headers, r)
+			top = max(top, r.Rect.Max.Y)
+		} else {
+			footers = append(footers, r)
+			bottom = min(bottom, r.Rect.Min.Y)
+		}
+	}
+	body := image.Rect(page.Min.X, top, page.Max.X, bottom)
+	if len(headers)+len(footers) == 0 || body.Empty() {
+		return nil
+	}
+
+	result := append(headers, Region{Rect: body, Kind: KindText})
+
+	return append(result, footers...)
+}
+
+func markMargin(regions []Region, kind Kind, maxHeight, minGap, maxNumberWidth int) {
+	// The band is the line closest to the edge of the page. It can have more
+	// than one region, e.g. a page number and a title.
+	var band image.Rectangle
+	for _, r := range regions {
+		if r.Kind != KindText {
+			continue
+		}
+		if band.Empty() ||
+			(kind == KindHeader && r.Rect.Min.Y < band.Min.Y) ||
+			(kind == KindFooter && r.Rect.Max.Y > band.Max.Y) {
+			band = r.Rect
+		}
+	}
+	if band.Empty() || band.Dy() > maxHeight {
+		return
+	}
+
+	inBand := []int{}
+	rest := 0
+	for j, r := range regions {
+		if r.Kind != KindText {
+			continue
+		}
+		if r.Rect.Min.Y < band.Max.Y && r.Rect.Max.Y > band.Min.Y && r.Rect.Dy() <= maxHeight {
+			inBand = append(inBand, j)
+			continue
+		}
+		rest++
+		// The margin must be clearly separated from the rest of the text
+		if kind == KindHeader && r.Rect.Min.Y-band.Max.Y < minGap {
+			return
+		}
+		if kind == KindFooter && band.Min.Y-r.Rect.Max.Y < minGap {
+			return
+		}
+	}
+	if rest == 0 {
+		return
+	}
+
+	for _, j := range inBand {
+		if regions[j].Rect.Dx() <= maxNumberWidth {
+			regions[j].Kind = KindPageNumber
+		} else {
+			regions[j].Kind = kind
+		}
+	}
+}
+
+func xyCut(mask *image.Gray, r image.Rectangle, opts Options) []image.Rectangle {
+	r = trim(mask, r)
+	if r.Empty() || r.Dx() < opts.MinWidth || r.Dy() < opts.MinHeight {
+		return nil
+	}
+
+	rowStart, rowEnd := widestGap(profile(mask, r, true))
+	colStart, colEnd := widestGap(profile(mask, r, false))
+	rowGap, colGap := rowEnd-rowStart, colEnd-colStart
+
+	// Pick the gap that is wider, relative to the minimum for its direction
+	cutRows := rowGap >= max(opts.MinBlockGap, 1)
+	cutCols := colGap >= max(opts.MinColumnGap, 1)
+	if cutRows && cutCols {
+		cutRows = rowGap*max(opts.MinColumnGap, 1) >= colGap*max(opts.MinBlockGap, 1)
+		cutCols = !cutRows
+	}
+
+	switch {
+	case cutRows:
+		top := image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+rowStart)
+		bottom := image.Rect(r.Min.X, r.Min.Y+rowEnd, r.Max.X, r.Max.Y)
+		return append(xyCut(mask, top, opts), xyCut(mask, bottom, opts)...)
+	case cutCols:
+		left := image.Rect(r.Min.X, r.Min.Y, r.Min.X+colStart, r.Max.Y)
+		right := image.Rect(r.Min.X+colEnd, r.Min.Y, r.Max.X, r.Max.Y)
+		if opts.RightToLeft {
+			return append(xyCut(mask, right, opts), xyCut(mask, left, opts)...)
+		}
+		return append(xyCut(mask, left, opts), xyCut(mask, right, opts)...)
+	}
+
+	return []image.Rectangle{r}
+}
+
+// profile returns the number of set pixels on each row (or column) of the
+// region
+func profile(mask *image.Gray, r image.Rectangle, rows bool) []int {
+	var result []int
+	if rows {
+		result = make([]int, r.Dy())
+	} else {
+		result = make([]int, r.Dx())
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		offset := mask.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if mask.Pix[offset+x-r.Min.X] == 0 {
+				continue
+			}
+			if rows {
+				result[y-r.Min.Y]++
+			} else {
+				result[x-r.Min.X]++
+			}
+		}
+	}
+
+	return result
+}
+
+// widestGap returns the start and the end (exclusive) of the longest run of
+// zeros in the profile that is surrounded by non zero values
+func widestGap(p []int) (int, int) {
+	bestStart, bestEnd := 0, 0
+	start := -1
+	for j, v := range p {
+		switch {
+		case v == 0 && start < 0:
+			start = j
+		case v != 0 && start >= 0:
+			if start > 0 && j-start > bestEnd-bestStart {
+				bestStart, bestEnd = start, j
+			}
+			start = -1
+		}
+	}
+
+	return bestStart, bestEnd
+}
+
+// trim returns the smallest rectangle inside r that contains all the set
+// pixels of r
+func trim(mask *image.Gray, r image.Rectangle) image.Rectangle {
+	rows := profile(mask, r, true)
+	cols := profile(mask, r, false)
+
+	first, last := nonZeroRange(rows)
+	if first < 0 {
+		return image.Rectangle{}
+	}
+	left, right := nonZeroRange(cols)
+
+	return image.Rect(r.Min.X+left, r.Min.Y+first, r.Min.X+right+1, r.Min.Y+last+1)
+}
+
+// nonZeroRange returns the index of the first and the last non zero value
+// or -1, -1 if all the values are zero
+func nonZeroRange(p []int) (int, int) {
+	first, last := -1, -1
+	for j, v := range p {
+		if v != 0 {
+			if first < 0 {
+				first = j
+			}
+			last = j
+		}
+	}
+
+	return first, last
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,164 @@
+package layout_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/layout"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// newMask returns a 200x200 mask with the given rectangles filled
+func newMask(rects ...image.Rectangle) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, 200, 200))
+	for _, r := range rects {
+		draw.Draw(mask, r, &image.Uniform{color.Gray{255}}, image.Point{}, draw.Src)
+	}
+	return mask
+}
+
+func rects(regions []Region) []image.Rectangle {
+	result := []image.Rectangle{}
+	for _, r := range regions {
+		result = append(result, r.Rect)
+	}
+	return result
+}
+
+var _ = Describe("Segment", func() {
+	opts := Options{MinColumnGap: 10, MinBlockGap: 5}
+
+	It("reads a title and two columns in order", func() {
+		title := image.Rect(10, 10, 190, 30)
+		left := image.Rect(10, 40, 90, 190)
+		right := image.Rect(110, 40, 190, 190)
+		mask := newMask(right, title, left)
+
+		Expect(rects(Segment(mask, opts))).To(Equal([]image.Rectangle{title, left, right}))
+	})
+
+	It("reads columns from right to left", func() {
+		left := image.Rect(10, 10, 90, 190)
+		right := image.Rect(110, 10, 190, 190)
+		mask := newMask(left, right)
+
+		rtl := opts
+		rtl.RightToLeft = true
+		Expect(rects(Segment(mask, rtl))).To(Equal([]image.Rectangle{right, left}))
+	})
+
+	It("doesn't split on gaps narrower than the minimum", func() {
+		mask := newMask(image.Rect(10, 10, 95, 190), image.Rect(100, 10, 190, 190))
+
+		Expect(rects(Segment(mask, opts))).To(Equal([]image.Rectangle{image.Rect(10, 10, 190, 190)}))
+	})
+
+	It("drops regions smaller than the minimum size", func() {
+		mask := newMask(image.Rect(10, 10, 190, 100), image.Rect(50, 150, 52, 152))
+
+		small := opts
+		small.MinWidth, small.MinHeight = 5, 5
+		Expect(rects(Segment(mask, small))).To(Equal([]image.Rectangle{image.Rect(10, 10, 190, 100)}))
+	})
+
+	It("returns nothing for an empty page", func() {
+		Expect(Segment(newMask(), opts)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("MarkCaptions", func() {
+	var regions []Region
+
+	BeforeEach(func() {
+		regions = []Region{
+			{Rect: image.Rect(10, 10, 190, 40), Kind: KindText},
+			{Rect: image.Rect(10, 50, 190, 150), Kind: KindFigure},
+			{Rect: image.Rect(20, 155, 180, 165), Kind: KindText},
+			{Rect: image.Rect(10, 175, 190, 190), Kind: KindText},
+		}
+	})
+
+	It("marks the short text right below the figure", func() {
+		MarkCaptions(regions, 10, 20)
+		Expect(regions[0].Kind).To(Equal(KindText))
+		Expect(regions[2].Kind).To(Equal(KindCaption))
+		Expect(regions[3].Kind).To(Equal(KindText))
+	})
+
+	It("falls back to the text right above the figure", func() {
+		regions[2].Kind = KindFigure
+		regions[3].Kind = KindFigure
+		MarkCaptions(regions, 10, 30)
+		Expect(regions[0].Kind).To(Equal(KindCaption))
+	})
+
+	It("ignores text that is too tall to be a caption", func() {
+		MarkCaptions(regions, 10, 5)
+		Expect(regions[2].Kind).To(Equal(KindText))
+	})
+})
+
+var _ = Describe("MarkMargins", func() {
+	var regions []Region
+
+	BeforeEach(func() {
+		regions = []Region{
+			{Rect: image.Rect(10, 5, 20, 15), Kind: KindText},
+			{Rect: image.Rect(60, 5, 190, 15), Kind: KindText},
+			{Rect: image.Rect(10, 40, 190, 160), Kind: KindText},
+			{Rect: image.Rect(95, 180, 105, 190), Kind: KindText},
+		}
+	})
+
+	It("marks the header, the footer and the page numbers", func() {
+		MarkMargins(regions, 12, 10, 15)
+		Expect(regions[0].Kind).To(Equal(KindPageNumber))
+		Expect(regions[1].Kind).To(Equal(KindHeader))
+		Expect(regions[2].Kind).To(Equal(KindText))
+		Expect(regions[3].Kind).To(Equal(KindPageNumber))
+	})
+
+	It("ignores lines that are close to the rest of the text", func() {
+		MarkMargins(regions, 12, 30, 15)
+		Expect(regions[1].Kind).To(Equal(KindText))
+		Expect(regions[3].Kind).To(Equal(KindText))
+	})
+
+	It("ignores lines that are too tall", func() {
+		MarkMargins(regions, 5, 10, 15)
+		Expect(regions[1].Kind).To(Equal(KindText))
+	})
+
+	It("doesn't mark a page with a single line", func() {
+		regions = regions[1:2]
+		MarkMargins(regions, 12, 10, 15)
+		Expect(regions[0].Kind).To(Equal(KindText))
+	})
+})
+
+var _ = Describe("Margins", func() {
+	page := image.Rect(0, 0, 200, 200)
+
+	It("keeps the margins and reads everything between them as one block", func() {
+		regions := []Region{
+			{Rect: image.Rect(10, 5, 20, 15), Kind: KindPageNumber},
+			{Rect: image.Rect(60, 5, 190, 15), Kind: KindHeader},
+			{Rect: image.Rect(10, 40, 90, 160), Kind: KindText},
+			{Rect: image.Rect(110, 40, 190, 160), Kind: KindText},
+			{Rect: image.Rect(95, 180, 105, 190), Kind: KindPageNumber},
+		}
+		Expect(Margins(regions, page)).To(Equal([]Region{
+			{Rect: image.Rect(10, 5, 20, 15), Kind: KindPageNumber},
+			{Rect: image.Rect(60, 5, 190, 15), Kind: KindHeader},
+			{Rect: image.Rect(0, 15, 200, 180), Kind: KindText},
+			{Rect: image.Rect(95, 180, 105, 190), Kind: KindPageNumber},
+		}))
+	})
+
+	It("returns nothing when there are no margins", func() {
+		regions := []Region{{Rect: image.Rect(10, 40, 190, 160), Kind: KindText}}
+		Expect(Margins(regions, page)).To(BeNil())
+	})
+})
@@ -23,7 +23,7 @@ func (l Logger) Log(msg string) {
 }
 
 func (l Logger) Logf(msg string, v ...any) {
-	l.InfoLogger.Printf(msg, v)
+	l.InfoLogger.Printf(msg, v...)
 }
 
 func (l Logger) Error(msg string) {
@@ -31,5 +31,5 @@ func (l Logger) Error(msg string) {
 }
 
 func (l Logger) Errorf(msg string, v ...any) {
-	l.ErrorLogger.Printf(msg, v)
+	l.ErrorLogger.Printf(msg, v...)
 }
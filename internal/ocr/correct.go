@@ -0,0 +1,300 @@
+package ocr
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	"github.com/pkg/errors"
+)
+
+// confusionCost is the cost of reading one of the confusions instead of what
+// is printed. Any other edit costs 1.
+const confusionCost = 0.3
+
+// maxAlphabet is the largest alphabet the words are edited with. Scripts with
+// more letters (e.g. Han) are only corrected for the confusions.
+const maxAlphabet = 100
+
+// confusions are the mistakes tesseract often makes, what it reads instead of
+// what is printed (in lower case)
+var confusions = []struct{ read, printed []rune }{
+	{[]rune("rn"), []rune("m")},
+	{[]rune("m"), []rune("rn")},
+	{[]rune("in"), []rune("m")},
+	{[]rune("cl"), []rune("d")},
+	{[]rune("vv"), []rune("w")},
+	{[]rune("li"), []rune("h")},
+	{[]rune("ii"), []rune("u")},
+	{[]rune("0"), []rune("o")},
+	{[]rune("1"), []rune("l")},
+	{[]rune("1"), []rune("i")},
+	{[]rune("|"), []rune("l")},
+	{[]rune("5"), []rune("s")},
+	{[]rune("8"), []rune("b")},
+	{[]rune("l"), []rune("i")},
+	{[]rune("i"), []rune("l")},
+	{[]rune("c"), []rune("e")},
+	{[]rune("e"), []rune("c")},
+	{[]rune("u"), []rune("n")},
+	{[]rune("n"), []rune("u")},
+	{[]rune("h"), []rune("b")},
+	{[]rune("f"), []rune("t")},
+	{[]rune("t"), []rune("f")},
+}
+
+// ligatures are replaced with their letters, which the text to speech can
+// pronounce
+var ligatures = strings.NewReplacer(
+	"\ufb00", "ff",
+	"\ufb01", "fi",
+	"\ufb02", "fl",
+	"\ufb03", "ffi",
+	"\ufb04", "ffl",
+	"\ufb05", "st",
+	"\ufb06", "st",
+)
+
+// Corrector fixes the words tesseract probably got wrong. Words that are not
+// in the wordlist of their language are replaced with the closest word of the
+// wordlist, when there is a single one. The less confident tesseract was
+// about a word, the further the replacement can be.
+type Corrector struct {
+	// MinConfidence (0-100) is the confidence from which words are kept as
+	// they are
+	MinConfidence float64
+	// Personal are the words of the user (e.g. names), which are known in
+	// all languages and never corrected
+	Personal *wordlist.Wordlist
+}
+
+// NewCorrector returns a Corrector that knows the words of the personal
+// dictionary
+func NewCorrector() (*Corrector, error) {
+	personal, err := wordlist.Personal()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating the corrector")
+	}
+
+	return &Corrector{MinConfidence: 80, Personal: personal}, nil
+}
+
+// alphabet returns the letters of the words or nil if there are too many
+func alphabet(words *wordlist.Wordlist) []rune {
+	letters := words.Letters()
+	if len(letters) > maxAlphabet {
+		return nil
+	}
+
+	return letters
+}
+
+// Correct replaces the ligatures and corrects the words of the result with
+// the wordlists of its languages. Nothing else is corrected when there is no
+// wordlist for them.
+func (c *Corrector) Correct(r *Result) {
+	words := wordlist.ForLanguages(r.Languages)
+	var letters []rune
+	if words != nil {
+		letters = alphabet(words)
+	}
+
+	for b := range r.Blocks {
+		for p := range r.Blocks[b].Paragraphs {
+			for l := range r.Blocks[b].Paragraphs[p].Lines {
+				line := r.Blocks[b].Paragraphs[p].Lines[l].Words
+				for w := range line {
+					line[w].Text = ligatures.Replace(line[w].Text)
+					if words == nil || line[w].Confidence >= c.MinConfidence {
+						continue
+					}
+					line[w].Text = c.correct(line[w].Text, line[w].Confidence, words, letters)
+				}
+			}
+		}
+	}
+}
+
+// known returns true if the word is in the wordlist or in the personal
+// dictionary
+func (c *Corrector) known(word string, words *wordlist.Wordlist) bool {
+	return words.Contains(word) || (c.Personal != nil && c.Personal.Contains(word))
+}
+
+// correct returns the word corrected with the words of the wordlist or as it
+// is if it is known or there is no single best correction. The personal
+// words are kept but never used as corrections, they are too few to tell
+// what a misread word should be.
+func (c *Corrector) correct(text string, confidence float64, words *wordlist.Wordlist, alphabet []rune) string {
+	// The punctuation around the word is kept
+	core := strings.TrimFunc(text, unicode.IsPunct)
+	if strings.IndexFunc(core, unicode.IsLetter) < 0 || c.known(core, words) {
+		return text
+	}
+	start := strings.Index(text, core)
+	lower := strings.ToLower(core)
+
+	// A confusion or two are always allowed and other edits only for the
+	// words tesseract wasn't sure about
+	budget := 2*confusionCost + 1 - confidence/c.MinConfidence
+	candidates := confused(lower)
+	if budget >= 1 && alphabet != nil {
+		candidates = append(candidates, edits(lower, alphabet)...)
+	}
+
+	best, bestCost, ambiguous := "", budget, false
+	for _, candidate := range candidates {
+		// The digits tesseract read are letters
+		if candidate == best || strings.IndexFunc(candidate, unicode.IsDigit) >= 0 || !words.Contains(candidate) {
+			continue
+		}
+		cost := Distance(lower, candidate)
+		switch {
+		case cost < bestCost-1e-9:
+			best, bestCost, ambiguous = candidate, cost, false
+		case cost <= bestCost+1e-9 && best != "":
+			ambiguous = true
+		}
+	}
+	if best == "" || ambiguous {
+		return text
+	}
+
+	return text[:start] + matchCase(best, core) + text[start+len(core):]
+}
+
+// confused returns the words the word could be if tesseract made up to two
+// of the confusions
+func confused(word string) []string {
+	seen := map[string]bool{word: true}
+	result := []string{}
+	current := []string{word}
+	for round := 0; round < 2; round++ {
+		next := []string{}
+		for _, w := range current {
+			runes := []rune(w)
+			for _, c := range confusions {
+				for j := 0; j+len(c.read) <= len(runes); j++ {
+					if !hasPrefix(runes[j:], c.read) {
+						continue
+					}
+					replaced := string(runes[:j]) + string(c.printed) + string(runes[j+len(c.read):])
+					if !seen[replaced] {
+						seen[replaced] = true
+						next = append(next, replaced)
+					}
+				}
+			}
+		}
+		result = append(result, next...)
+		current = next
+	}
+
+	return result
+}
+
+// edits returns the words one deletion, transposition, replacement or
+// insertion away from the word
+func edits(word string, alphabet []rune) []string {
+	runes := []rune(word)
+	result := []string{}
+	for j := 0; j <= len(runes); j++ {
+		head, tail := string(runes[:j]), runes[j:]
+		if len(tail) > 0 {
+			result = append(result, head+string(tail[1:]))
+		}
+		if len(tail) > 1 {
+			result = append(result, head+string(tail[1])+string(tail[0])+string(tail[2:]))
+		}
+		for _, r := range alphabet {
+			if len(tail) > 0 {
+				result = append(result, head+string(r)+string(tail[1:]))
+			}
+			result = append(result, head+string(r)+string(tail))
+		}
+	}
+
+	return result
+}
+
+// Distance is the edit distance between what tesseract read and a word,
+// counting transpositions as one edit. The confusions of tesseract cost less
+// than the other edits.
+func Distance(read, word string) float64 {
+	a, b := []rune(read), []rune(word)
+	d := make([][]float64, len(a)+1)
+	for i := range d {
+		d[i] = make([]float64, len(b)+1)
+		d[i][0] = float64(i)
+	}
+	for j := range d[0] {
+		d[0][j] = float64(j)
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			substitution := 1.0
+			if a[i-1] == b[j-1] {
+				substitution = 0
+			}
+			d[i][j] = minFloat(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+substitution)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minFloat(d[i][j], d[i-2][j-2]+1)
+			}
+			for _, c := range confusions {
+				ri, pj := i-len(c.read), j-len(c.printed)
+				if ri < 0 || pj < 0 || !hasPrefix(a[ri:i], c.read) || !hasPrefix(b[pj:j], c.printed) {
+					continue
+				}
+				d[i][j] = minFloat(d[i][j], d[ri][pj]+confusionCost)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+// matchCase returns the word in the case of the original: upper case,
+// capitalized or lower case
+func matchCase(word, original string) string {
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, original)
+	if utf8.RuneCountInString(letters) > 1 && strings.ToUpper(letters) == letters {
+		return strings.ToUpper(word)
+	}
+	if first, _ := utf8.DecodeRuneInString(original); unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(word)
+		return string(unicode.ToUpper(r)) + word[size:]
+	}
+
+	return word
+}
+
+func hasPrefix(runes, prefix []rune) bool {
+	if len(runes) < len(prefix) {
+		return false
+	}
+	for j := range prefix {
+		if runes[j] != prefix[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func minFloat(values ...float64) float64 {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
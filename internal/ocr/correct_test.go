@@ -0,0 +1,94 @@
+package ocr_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Corrector", func() {
+	var corrector *Corrector
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		words := "the\nmodern\nother\nlike\nfind\nstorm\nform\nsea\nsad\n"
+		Expect(os.WriteFile(filepath.Join(dir, "tst.txt"), []byte(words), 0644)).To(Succeed())
+		os.Setenv("OOR_WORDLIST_DIR", dir)
+		DeferCleanup(os.Unsetenv, "OOR_WORDLIST_DIR")
+
+		corrector = &Corrector{MinConfidence: 80, Personal: wordlist.New("fom", "karras")}
+	})
+
+	correctIn := func(languages []string, text string, confidence float64) string {
+		result := NewResult([]WordBox{word(text, 0, confidence, 1, 1, 1)})
+		result.Languages = languages
+		corrector.Correct(result)
+		return result.Blocks[0].Paragraphs[0].Lines[0].Words[0].Text
+	}
+
+	correct := func(text string, confidence float64) string {
+		return correctIn([]string{"tst"}, text, confidence)
+	}
+
+	It("fixes the confusions of tesseract", func() {
+		Expect(correct("rnodern", 70)).To(Equal("modern"))
+		Expect(correct("0ther,", 70)).To(Equal("other,"))
+		Expect(correct("(1ike)", 70)).To(Equal("(like)"))
+		Expect(correct("THE", 70)).To(Equal("THE"))
+	})
+
+	It("keeps the case of the word", func() {
+		Expect(correct("Rnodern", 70)).To(Equal("Modern"))
+		Expect(correct("0THER", 70)).To(Equal("OTHER"))
+	})
+
+	It("replaces the ligatures", func() {
+		Expect(correct("ﬁnd", 95)).To(Equal("find"))
+	})
+
+	It("only makes other edits when tesseract wasn't sure", func() {
+		Expect(correct("stonn", 70)).To(Equal("stonn"))
+		Expect(correct("stoorm", 30)).To(Equal("storm"))
+	})
+
+	It("keeps the words tesseract was sure about", func() {
+		Expect(correct("rnodern", 90)).To(Equal("rnodern"))
+	})
+
+	It("keeps the word when there is no single best correction", func() {
+		// "sed" is one edit away from both "sea" and "sad"
+		Expect(correct("sed", 20)).To(Equal("sed"))
+	})
+
+	It("keeps numbers", func() {
+		Expect(correct("1984", 20)).To(Equal("1984"))
+	})
+
+	It("keeps the words of the personal dictionary", func() {
+		// "Fom" is one insertion away from "form"
+		Expect(correct("Fom", 20)).To(Equal("Fom"))
+	})
+
+	It("doesn't correct to the words of the personal dictionary", func() {
+		Expect(correct("karas", 20)).To(Equal("karas"))
+	})
+
+	It("only replaces the ligatures when there is no wordlist for the languages", func() {
+		Expect(correctIn([]string{"xxx"}, "\ufb01nd", 95)).To(Equal("find"))
+		Expect(correctIn([]string{"xxx"}, "rnodern", 20)).To(Equal("rnodern"))
+		Expect(correctIn(nil, "stoorm", 20)).To(Equal("stoorm"))
+	})
+})
+
+var _ = Describe("Distance", func() {
+	It("costs less for the confusions of tesseract", func() {
+		Expect(Distance("rnodern", "modern")).To(BeNumerically("<", 1))
+		Expect(Distance("stoorm", "storm")).To(Equal(1.0))
+		Expect(Distance("sotrm", "storm")).To(Equal(1.0))
+		Expect(Distance("storm", "storm")).To(Equal(0.0))
+	})
+})
@@ -0,0 +1,17 @@
+package ocr
+
+import "github.com/otiai10/gosseract/v2"
+
+// The methods below are exported for the tests of the ocr_test package
+
+func (p *Pool) Acquire(languages []string, psm gosseract.PageSegMode) (*gosseract.Client, error) {
+	return p.acquire(languages, psm)
+}
+
+func (p *Pool) Release(client *gosseract.Client, languages []string, psm gosseract.PageSegMode) {
+	p.release(client, languages, psm)
+}
+
+func (p *Pool) Run(f func() error) error {
+	return p.run(f)
+}
@@ -0,0 +1,132 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/langdetect"
+	"github.com/otiai10/gosseract/v2"
+	"github.com/pkg/errors"
+)
+
+// ParseLanguages splits a tesseract language specification (e.g. "eng" or
+// "eng+ell"). It returns nil, which means "detect the language", for an empty
+// specification.
+func ParseLanguages(spec string) []string {
+	languages := []string{}
+	for _, l := range strings.Split(spec, "+") {
+		if l = strings.TrimSpace(l); l != "" {
+			languages = append(languages, l)
+		}
+	}
+	if len(languages) == 0 {
+		return nil
+	}
+
+	return languages
+}
+
+// LanguagesFromEnv returns the languages set with the OOR_LANG environment
+// variable. It returns nil, which means "detect the language", if it is not
+// set.
+func LanguagesFromEnv() []string {
+	return ParseLanguages(os.Getenv("OOR_LANG"))
+}
+
+// recognizeAnyLanguage detects the script of the image with tesseract and
+// then reads the image with the most common language of that script. When
+// there is more than one installed language for the script, the language of
+// the text is detected from its character n-grams and, if it isn't the most
+// common one, the image is read again in the detected language.
+func (t TesseractOCR) recognizeAnyLanguage(data []byte) (*Result, error) {
+	candidates := []string{}
+	// Without the orientation and script detection data, assume the most
+	// common script
+	script, err := t.detectScript(data)
+	if errors.Is(err, ErrBusy) {
+		return nil, err
+	}
+	if err == nil {
+		candidates = installed(langdetect.ForScript(script))
+	}
+	if len(candidates) == 0 {
+		candidates = installed(langdetect.ForScript("Latin"))
+	}
+	if len(candidates) == 0 {
+		candidates = []string{"eng"}
+	}
+
+	result, err := t.recognize(data, candidates[:1])
+	if err != nil || len(candidates) == 1 {
+		return result, err
+	}
+
+	guesses := langdetect.Detect(result.Text(), candidates...)
+	if len(guesses) == 0 || guesses[0].Language == candidates[0] {
+		return result, nil
+	}
+
+	return t.recognize(data, []string{guesses[0].Language})
+}
+
+// detectScript runs detectScript in place of a client of the pool, if there
+// is one, so that the tesseract commands count towards its size
+func (t TesseractOCR) detectScript(data []byte) (string, error) {
+	if t.Pool == nil {
+		return detectScript(data)
+	}
+
+	var script string
+	err := t.Pool.run(func() error {
+		var err error
+		script, err = detectScript(data)
+		return err
+	})
+
+	return script, err
+}
+
+// detectScript returns the script of the text in the encoded image (e.g.
+// "Latin" or "Greek") as detected by the orientation and script detection
+// of tesseract. The tesseract library doesn't expose it, so this runs the
+// tesseract command, which needs the "osd" language data to be installed.
+func detectScript(data []byte) (string, error) {
+	cmd := exec.Command("tesseract", "stdin", "stdout", "--psm", "0")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "running the tesseract script detection")
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "Script: ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Script: ")), nil
+		}
+	}
+
+	return "", errors.New("no script in the tesseract script detection output")
+}
+
+// installed returns the languages that have tesseract data installed
+func installed(languages []string) []string {
+	available, err := gosseract.GetAvailableLanguages()
+	if err != nil {
+		return nil
+	}
+
+	result := []string{}
+	for _, l := range languages {
+		for _, a := range available {
+			if l == a {
+				result = append(result, l)
+				break
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,19 @@
+package ocr_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseLanguages", func() {
+	It("splits multi language specifications", func() {
+		Expect(ParseLanguages("eng+ell")).To(Equal([]string{"eng", "ell"}))
+		Expect(ParseLanguages(" deu ")).To(Equal([]string{"deu"}))
+	})
+
+	It("returns nil when there are no languages", func() {
+		Expect(ParseLanguages("")).To(BeNil())
+		Expect(ParseLanguages("+")).To(BeNil())
+	})
+})
@@ -2,45 +2,124 @@
 package ocr
 
 import (
-	"os"
-
 	"github.com/jimmykarily/open-ocr-reader/internal/img"
 	"github.com/otiai10/gosseract/v2"
 	"github.com/pkg/errors"
 )
 
 type OCR interface {
+	// Parse returns the text in the image
 	Parse(img *img.Image) (string, error)
+	// Recognize returns the text in the image with its layout and the
+	// confidence of every word
+	Recognize(img *img.Image) (*Result, error)
 }
 
-type TesseractOCR struct{}
+// TesseractOCR runs OCR with tesseract
+type TesseractOCR struct {
+	// Languages are the tesseract languages of the text (e.g. "eng" and
+	// "ell" for a page with both English and Greek). When empty, the
+	// language is detected on every image.
+	Languages []string
+	// PageSegMode is how tesseract splits the page in blocks and lines.
+	// The default (fully automatic segmentation) is used when it is 0,
+	// which would only detect the orientation and the script.
+	PageSegMode gosseract.PageSegMode
+	// Pool, when set, provides the tesseract clients. Otherwise a new
+	// client is created for every image.
+	Pool *Pool
+}
 
-func NewTesseractOCR() TesseractOCR {
-	return TesseractOCR{}
+func NewTesseractOCR(languages []string) TesseractOCR {
+	return TesseractOCR{Languages: languages}
 }
 
+// Parse returns the plain text of Recognize
 func (t TesseractOCR) Parse(img *img.Image) (string, error) {
+	result, err := t.Recognize(img)
+	if err != nil {
+		return "", err
+	}
+
+	return result.Text(), nil
+}
+
+func (t TesseractOCR) Recognize(img *img.Image) (*Result, error) {
 	//l, _ := gosseract.GetAvailableLanguages()
 	//fmt.Printf("l = %+v\n", l)
 
-	imgPath, err := img.StoreTmp()
+	// A lossless format, so that tesseract sees exactly what the processor
+	// produced
+	data, err := img.EncodeLossless()
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding the image")
+	}
+
+	if len(t.Languages) == 0 {
+		return t.recognizeAnyLanguage(data)
+	}
+
+	return t.recognize(data, t.Languages)
+}
+
+// recognize runs tesseract on the encoded image
+func (t TesseractOCR) recognize(data []byte, languages []string) (*Result, error) {
+	boxes, err := t.boxes(data, languages)
 	if err != nil {
-		return "", errors.Wrap(err, "storing the image to a temp file")
+		return nil, err
 	}
-	defer os.Remove(imgPath)
 
-	client := gosseract.NewClient()
-	lang := os.Getenv("OOR_LANG")
-	if lang == "" {
-		lang = "eng"
+	words := []WordBox{}
+	for _, b := range boxes {
+		words = append(words, WordBox{
+			Word:     Word{Box: b.Box, Confidence: b.Confidence, Text: b.Word},
+			BlockNum: b.BlockNum,
+			ParNum:   b.ParNum,
+			LineNum:  b.LineNum,
+		})
+	}
+
+	result := NewResult(words)
+	result.Languages = languages
+
+	return result, nil
+}
+
+// boxes returns the words tesseract found in the encoded image, using a
+// client of the pool if there is one
+func (t TesseractOCR) boxes(data []byte, languages []string) ([]gosseract.BoundingBox, error) {
+	var client *gosseract.Client
+	var err error
+	if t.Pool != nil {
+		client, err = t.Pool.acquire(languages, t.PageSegMode)
+	} else {
+		client, err = newClient(languages, t.PageSegMode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	boxes, err := readBoxes(client, data)
+	switch {
+	case t.Pool == nil:
+		client.Close()
+	case err != nil:
+		t.Pool.discard(client)
+	default:
+		t.Pool.release(client, languages, t.PageSegMode)
+	}
+
+	return boxes, err
+}
+
+func readBoxes(client *gosseract.Client, data []byte) ([]gosseract.BoundingBox, error) {
+	if err := client.SetImageFromBytes(data); err != nil {
+		return nil, errors.Wrap(err, "passing the image to tesseract")
 	}
-	client.Languages = []string{lang}
-	defer client.Close()
-	client.SetImage(imgPath)
-	text, err := client.Text()
+	boxes, err := client.GetBoundingBoxesVerbose()
 	if err != nil {
-		return "", errors.Wrap(err, "detecting text")
+		return nil, errors.Wrap(err, "detecting text")
 	}
 
-	return text, nil
+	return boxes, nil
 }
@@ -0,0 +1,52 @@
+package ocr_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/otiai10/gosseract/v2"
+)
+
+// BenchmarkParseThroughDisk is how the images used to be passed to
+// tesseract: as a JPEG temp file. Compare with BenchmarkParse to see the
+// latency saved per page:
+//
+//	go test -bench . ./internal/ocr/
+func BenchmarkParseThroughDisk(b *testing.B) {
+	image, err := img.New("../../assets/sample-wikipedia.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		path, err := image.StoreTmp()
+		if err != nil {
+			b.Fatal(err)
+		}
+		client := gosseract.NewClient()
+		client.SetImage(path)
+		if _, err := client.Text(); err != nil {
+			b.Fatal(err)
+		}
+		client.Close()
+		os.Remove(path)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	image, err := img.New("../../assets/sample-wikipedia.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	t := ocr.NewTesseractOCR([]string{"eng"})
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := t.Parse(image); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
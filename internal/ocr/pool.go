@@ -0,0 +1,297 @@
+package ocr
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otiai10/gosseract/v2"
+	"github.com/pkg/errors"
+)
+
+// ErrBusy is returned when all the clients of a Pool stay busy for longer
+// than the maximum wait of the Pool
+var ErrBusy = errors.New("all the OCR engines are busy")
+
+// Pool keeps tesseract clients around so that the language data is loaded
+// once per client instead of once per image. There is a client per language
+// and page segmentation mode combination. Size limits the number of clients
+// (busy and idle) and callers wait for a free one when all are busy.
+type Pool struct {
+	size    int
+	maxWait time.Duration
+	// tokens has one token per client that can be in use
+	tokens chan struct{}
+
+	mu    sync.Mutex
+	idle  map[string][]*gosseract.Client
+	stats PoolStats
+	// closed is set by Close, the clients released after it are closed
+	closed bool
+}
+
+// PoolStats are the metrics of a Pool
+type PoolStats struct {
+	Size int
+	// Busy and Idle are the clients in use and waiting to be used
+	Busy int
+	Idle int
+	// Waiting is the number of callers waiting for a client
+	Waiting int
+	// Acquired counts the clients handed out and Rejected the callers that
+	// gave up waiting
+	Acquired int
+	Rejected int
+	// Created and Evicted count the clients started and closed. Clients are
+	// evicted to make room for a client of another language.
+	Created int
+	Evicted int
+	// Waited is the total time callers waited for a client
+	Waited time.Duration
+}
+
+// NewPool returns a Pool with at most size clients. Callers wait at most
+// maxWait for a client.
+func NewPool(size int, maxWait time.Duration) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		size:    size,
+		maxWait: maxWait,
+		tokens:  make(chan struct{}, size),
+		idle:    map[string][]*gosseract.Client{},
+	}
+	p.stats.Size = size
+
+	return p
+}
+
+// NewPoolFromEnv returns a Pool with OOR_OCR_POOL_SIZE clients (the number
+// of CPUs by default) where callers wait for OOR_OCR_POOL_WAIT (e.g. "10s",
+// 30 seconds by default)
+func NewPoolFromEnv() (*Pool, error) {
+	size := runtime.NumCPU()
+	if v := os.Getenv("OOR_OCR_POOL_SIZE"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrap(err, "parsing OOR_OCR_POOL_SIZE")
+		}
+	}
+
+	maxWait := 30 * time.Second
+	if v := os.Getenv("OOR_OCR_POOL_WAIT"); v != "" {
+		var err error
+		if maxWait, err = time.ParseDuration(v); err != nil {
+			return nil, errors.Wrap(err, "parsing OOR_OCR_POOL_WAIT")
+		}
+	}
+
+	return NewPool(size, maxWait), nil
+}
+
+// poolKey identifies the clients that can be reused for a configuration
+func poolKey(languages []string, psm gosseract.PageSegMode) string {
+	return strings.Join(languages, "+") + "/" + strconv.Itoa(int(psm))
+}
+
+// wait takes one of the tokens. It waits for a free one up to maxWait and
+// then returns ErrBusy.
+func (p *Pool) wait() error {
+	p.mu.Lock()
+	p.stats.Waiting++
+	p.mu.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(p.maxWait)
+	defer timer.Stop()
+	select {
+	case p.tokens <- struct{}{}:
+	case <-timer.C:
+		p.mu.Lock()
+		p.stats.Waiting--
+		p.stats.Rejected++
+		p.stats.Waited += time.Since(start)
+		p.mu.Unlock()
+		return errors.WithStack(ErrBusy)
+	}
+
+	p.mu.Lock()
+	p.stats.Waiting--
+	p.stats.Waited += time.Since(start)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// acquire returns a client for the languages and the page segmentation mode.
+// It waits for a free client up to maxWait and then returns ErrBusy.
+func (p *Pool) acquire(languages []string, psm gosseract.PageSegMode) (*gosseract.Client, error) {
+	if err := p.wait(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Acquired++
+	p.stats.Busy++
+
+	key := poolKey(languages, psm)
+	if clients := p.idle[key]; len(clients) > 0 {
+		p.idle[key] = clients[:len(clients)-1]
+		p.stats.Idle--
+		return clients[len(clients)-1], nil
+	}
+
+	// Make room for the new client. There is an idle one to close because
+	// this caller holds one of the tokens.
+	if p.stats.Busy-1+p.stats.Idle >= p.size {
+		p.evictOne()
+	}
+
+	client, err := newClient(languages, psm)
+	if err != nil {
+		p.stats.Busy--
+		<-p.tokens
+		return nil, err
+	}
+	p.stats.Created++
+
+	return client, nil
+}
+
+// release gives a client back to the pool, or closes it if the pool is
+// closed
+func (p *Pool) release(client *gosseract.Client, languages []string, psm gosseract.PageSegMode) {
+	p.mu.Lock()
+	if p.closed {
+		client.Close()
+	} else {
+		key := poolKey(languages, psm)
+		p.idle[key] = append(p.idle[key], client)
+		p.stats.Idle++
+	}
+	p.stats.Busy--
+	p.mu.Unlock()
+
+	<-p.tokens
+}
+
+// discard closes a client instead of giving it back to the pool, e.g.
+// after it failed
+func (p *Pool) discard(client *gosseract.Client) {
+	client.Close()
+	p.mu.Lock()
+	p.stats.Busy--
+	p.mu.Unlock()
+
+	<-p.tokens
+}
+
+// run calls f in place of a client, for the tesseract work that can't be
+// done with one (e.g. running the tesseract command). It waits like acquire,
+// so that the work counts towards the size of the pool.
+func (p *Pool) run(f func() error) error {
+	if err := p.wait(); err != nil {
+		return err
+	}
+	defer func() { <-p.tokens }()
+
+	return f()
+}
+
+// evictOne closes an idle client. The lock must be held.
+func (p *Pool) evictOne() {
+	for key, clients := range p.idle {
+		if len(clients) == 0 {
+			continue
+		}
+		clients[0].Close()
+		p.idle[key] = clients[1:]
+		p.stats.Idle--
+		p.stats.Evicted++
+		return
+	}
+}
+
+// WarmUp starts n clients for the languages and loads their data, so that
+// the first requests don't have to wait for it
+func (p *Pool) WarmUp(languages []string, n int) error {
+	clients := []*gosseract.Client{}
+	defer func() {
+		for _, c := range clients {
+			p.release(c, languages, 0)
+		}
+	}()
+
+	for j := 0; j < n && j < p.size; j++ {
+		client, err := p.acquire(languages, 0)
+		if err != nil {
+			return err
+		}
+		clients = append(clients, client)
+		// The data is loaded on the first image
+		if err := client.SetImageFromBytes(blankImage()); err != nil {
+			return errors.Wrap(err, "passing the warm up image to tesseract")
+		}
+		if _, err := client.Text(); err != nil {
+			return errors.Wrap(err, "warming up tesseract")
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the current metrics of the pool
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stats
+}
+
+// Close closes all the idle clients. The busy ones are closed when they are
+// released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	for key, clients := range p.idle {
+		for _, c := range clients {
+			c.Close()
+		}
+		p.stats.Idle -= len(clients)
+		delete(p.idle, key)
+	}
+}
+
+// newClient returns a tesseract client for the languages and the page
+// segmentation mode. The default mode is used when psm is 0.
+func newClient(languages []string, psm gosseract.PageSegMode) (*gosseract.Client, error) {
+	client := gosseract.NewClient()
+	client.Languages = languages
+	if psm != gosseract.PSM_OSD_ONLY {
+		if err := client.SetPageSegMode(psm); err != nil {
+			client.Close()
+			return nil, errors.Wrap(err, "setting the page segmentation mode")
+		}
+	}
+
+	return client, nil
+}
+
+// blankImage returns a small white image in the PGM format
+func blankImage() []byte {
+	header := []byte("P5\n32 32\n255\n")
+	pixels := make([]byte, 32*32)
+	for j := range pixels {
+		pixels[j] = 255
+	}
+
+	return append(header, pixels...)
+}
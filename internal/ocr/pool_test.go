@@ -0,0 +1,126 @@
+package ocr_test
+
+import (
+	"time"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Pool", func() {
+	var pool *Pool
+	eng, ell := []string{"eng"}, []string{"ell"}
+
+	AfterEach(func() {
+		pool.Close()
+	})
+
+	It("reuses the idle clients", func() {
+		pool = NewPool(2, time.Second)
+		first, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		pool.Release(first, eng, 0)
+
+		second, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		stats := pool.Stats()
+		Expect(stats.Busy).To(Equal(1))
+		Expect(stats.Idle).To(Equal(0))
+		Expect(stats.Acquired).To(Equal(2))
+		Expect(stats.Created).To(Equal(1))
+		pool.Release(second, eng, 0)
+		Expect(pool.Stats().Idle).To(Equal(1))
+	})
+
+	It("returns ErrBusy when all the clients stay busy", func() {
+		pool = NewPool(1, 20*time.Millisecond)
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = pool.Acquire(eng, 0)
+		Expect(err).To(MatchError(ErrBusy))
+		Expect(pool.Stats().Rejected).To(Equal(1))
+		Expect(pool.Stats().Waited).To(BeNumerically(">=", 20*time.Millisecond))
+
+		pool.Release(client, eng, 0)
+		client, err = pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		pool.Release(client, eng, 0)
+	})
+
+	It("waits for a client to be released", func() {
+		pool = NewPool(1, time.Second)
+		busy, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(50 * time.Millisecond)
+			pool.Release(busy, eng, 0)
+		}()
+
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.Stats().Waited).To(BeNumerically(">=", 40*time.Millisecond))
+		pool.Release(client, eng, 0)
+	})
+
+	It("evicts an idle client to make room for another language", func() {
+		pool = NewPool(1, time.Second)
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		pool.Release(client, eng, 0)
+
+		client, err = pool.Acquire(ell, 0)
+		Expect(err).ToNot(HaveOccurred())
+		stats := pool.Stats()
+		Expect(stats.Busy).To(Equal(1))
+		Expect(stats.Idle).To(Equal(0))
+		Expect(stats.Created).To(Equal(2))
+		Expect(stats.Evicted).To(Equal(1))
+		pool.Release(client, ell, 0)
+	})
+
+	It("counts the work done without a client towards the size", func() {
+		pool = NewPool(1, 20*time.Millisecond)
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.Run(func() error { return nil })).To(MatchError(ErrBusy))
+		pool.Release(client, eng, 0)
+
+		failed := errors.New("no script")
+		Expect(pool.Run(func() error { return failed })).To(MatchError(failed))
+		Expect(pool.Run(func() error { return nil })).To(Succeed())
+	})
+
+	It("starts the clients when warming up", func() {
+		pool = NewPool(2, time.Second)
+		Expect(pool.WarmUp(eng, 2)).To(Succeed())
+		stats := pool.Stats()
+		Expect(stats.Busy).To(Equal(0))
+		Expect(stats.Idle).To(Equal(2))
+		Expect(stats.Created).To(Equal(2))
+
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.Stats().Created).To(Equal(2))
+		pool.Release(client, eng, 0)
+
+		pool.Close()
+		Expect(pool.Stats().Idle).To(BeZero())
+	})
+
+	It("closes the clients released after Close", func() {
+		pool = NewPool(2, time.Second)
+		client, err := pool.Acquire(eng, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		pool.Close()
+		pool.Release(client, eng, 0)
+		stats := pool.Stats()
+		Expect(stats.Busy).To(BeZero())
+		Expect(stats.Idle).To(BeZero())
+	})
+})
@@ -0,0 +1,226 @@
+package ocr
+
+import (
+	"image"
+	"strings"
+)
+
+// Result is the text found in an image with its layout. Every element has
+// its bounding box (in the coordinates of the image) and the confidence of
+// tesseract (0-100).
+type Result struct {
+	Blocks []Block
+	// Languages are the tesseract languages the text was read with
+	Languages []string
+}
+
+// Block is a block of text (e.g. a column)
+type Block struct {
+	Box        image.Rectangle
+	Confidence float64
+	Paragraphs []Paragraph
+}
+
+// Paragraph is a paragraph of a block
+type Paragraph struct {
+	Box        image.Rectangle
+	Confidence float64
+	Lines      []Line
+}
+
+// Line is a line of text of a paragraph
+type Line struct {
+	Box        image.Rectangle
+	Confidence float64
+	Words      []Word
+}
+
+// Word is a single word
+type Word struct {
+	Box        image.Rectangle
+	Confidence float64
+	Text       string
+}
+
+// WordBox is a recognized word and its position in the layout. The numbers
+// start from 1 and restart in every parent (e.g. LineNum restarts in every
+// paragraph), like in the TSV output of tesseract.
+type WordBox struct {
+	Word
+	BlockNum, ParNum, LineNum int
+}
+
+// NewResult builds the layout from the words in reading order
+func NewResult(words []WordBox) *Result {
+	r := &Result{}
+	var block *Block
+	var par *Paragraph
+	var line *Line
+	lastBlock, lastPar, lastLine := -1, -1, -1
+	for _, w := range words {
+		if w.BlockNum != lastBlock {
+			r.Blocks = append(r.Blocks, Block{})
+			block = &r.Blocks[len(r.Blocks)-1]
+			lastPar = -1
+		}
+		if w.ParNum != lastPar {
+			block.Paragraphs = append(block.Paragraphs, Paragraph{})
+			par = &block.Paragraphs[len(block.Paragraphs)-1]
+			lastLine = -1
+		}
+		if w.LineNum != lastLine {
+			par.Lines = append(par.Lines, Line{})
+			line = &par.Lines[len(par.Lines)-1]
+		}
+		line.Words = append(line.Words, w.Word)
+		lastBlock, lastPar, lastLine = w.BlockNum, w.ParNum, w.LineNum
+	}
+	r.update()
+
+	return r
+}
+
+// update sets the boxes and the confidences of the blocks, paragraphs and
+// lines from their words
+func (r *Result) update() {
+	for b := range r.Blocks {
+		block := &r.Blocks[b]
+		block.Box, block.Confidence = image.Rectangle{}, 0
+		for p := range block.Paragraphs {
+			par := &block.Paragraphs[p]
+			par.Box, par.Confidence = image.Rectangle{}, 0
+			for l := range par.Lines {
+				line := &par.Lines[l]
+				line.Box, line.Confidence = image.Rectangle{}, 0
+				for _, w := range line.Words {
+					line.Box = line.Box.Union(w.Box)
+				}
+				line.Confidence = meanConfidence(line.Words)
+				par.Box = par.Box.Union(line.Box)
+			}
+			par.Confidence = meanConfidence(par.Words())
+			block.Box = block.Box.Union(par.Box)
+		}
+		block.Confidence = meanConfidence(block.Words())
+	}
+}
+
+// Text returns the text with a line break after every line and an empty
+// line after every paragraph, like tesseract does
+func (r *Result) Text() string {
+	pars := []string{}
+	for _, b := range r.Blocks {
+		for _, p := range b.Paragraphs {
+			lines := []string{}
+			for _, l := range p.Lines {
+				lines = append(lines, l.Text())
+			}
+			pars = append(pars, strings.Join(lines, "\n"))
+		}
+	}
+	if len(pars) == 0 {
+		return ""
+	}
+
+	return strings.Join(pars, "\n\n") + "\n"
+}
+
+// Words returns all the words in reading order
+func (r *Result) Words() []Word {
+	words := []Word{}
+	for _, b := range r.Blocks {
+		words = append(words, b.Words()...)
+	}
+
+	return words
+}
+
+// Confidence returns the mean confidence of all the words or 0 if there are
+// no words
+func (r *Result) Confidence() float64 {
+	return meanConfidence(r.Words())
+}
+
+// Translate moves everything by offset, e.g. to go from the coordinates of a
+// region to the coordinates of the page the region was cropped from
+func (r *Result) Translate(offset image.Point) {
+	for b := range r.Blocks {
+		for p := range r.Blocks[b].Paragraphs {
+			for l := range r.Blocks[b].Paragraphs[p].Lines {
+				words := r.Blocks[b].Paragraphs[p].Lines[l].Words
+				for w := range words {
+					words[w].Box = words[w].Box.Add(offset)
+				}
+			}
+		}
+	}
+	r.update()
+}
+
+// Mapped returns a copy of the result with the boxes of the words mapped with
+// f, e.g. to the coordinates of the photo the image was made from
+func (r *Result) Mapped(f func(image.Rectangle) image.Rectangle) *Result {
+	c := &Result{Languages: r.Languages}
+	for _, b := range r.Blocks {
+		block := Block{}
+		for _, p := range b.Paragraphs {
+			par := Paragraph{}
+			for _, l := range p.Lines {
+				line := Line{}
+				for _, w := range l.Words {
+					w.Box = f(w.Box)
+					line.Words = append(line.Words, w)
+				}
+				par.Lines = append(par.Lines, line)
+			}
+			block.Paragraphs = append(block.Paragraphs, par)
+		}
+		c.Blocks = append(c.Blocks, block)
+	}
+	c.update()
+
+	return c
+}
+
+// Words returns the words of the block in reading order
+func (b Block) Words() []Word {
+	words := []Word{}
+	for _, p := range b.Paragraphs {
+		words = append(words, p.Words()...)
+	}
+
+	return words
+}
+
+// Words returns the words of the paragraph in reading order
+func (p Paragraph) Words() []Word {
+	words := []Word{}
+	for _, l := range p.Lines {
+		words = append(words, l.Words...)
+	}
+
+	return words
+}
+
+// Text returns the words of the line separated by spaces
+func (l Line) Text() string {
+	texts := []string{}
+	for _, w := range l.Words {
+		texts = append(texts, w.Text)
+	}
+
+	return strings.Join(texts, " ")
+}
+
+func meanConfidence(words []Word) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, w := range words {
+		sum += w.Confidence
+	}
+
+	return sum / float64(len(words))
+}
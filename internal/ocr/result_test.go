@@ -0,0 +1,65 @@
+package ocr_test
+
+import (
+	"image"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func word(text string, x int, confidence float64, block, par, line int) WordBox {
+	return WordBox{
+		Word:     Word{Text: text, Box: image.Rect(x, 10*line, x+10, 10*line+8), Confidence: confidence},
+		BlockNum: block, ParNum: par, LineNum: line,
+	}
+}
+
+var _ = Describe("NewResult", func() {
+	var result *Result
+
+	BeforeEach(func() {
+		result = NewResult([]WordBox{
+			word("The", 0, 90, 1, 1, 1),
+			word("storm", 20, 80, 1, 1, 1),
+			word("came.", 0, 70, 1, 1, 2),
+			word("It", 0, 60, 1, 2, 1),
+			word("Notes", 0, 50, 2, 1, 1),
+		})
+	})
+
+	It("builds the layout", func() {
+		Expect(result.Blocks).To(HaveLen(2))
+		Expect(result.Blocks[0].Paragraphs).To(HaveLen(2))
+		Expect(result.Blocks[0].Paragraphs[0].Lines).To(HaveLen(2))
+		Expect(result.Blocks[0].Paragraphs[0].Lines[0].Words).To(HaveLen(2))
+	})
+
+	It("sets the boxes and the confidences from the words", func() {
+		line := result.Blocks[0].Paragraphs[0].Lines[0]
+		Expect(line.Box).To(Equal(image.Rect(0, 10, 30, 18)))
+		Expect(line.Confidence).To(Equal(85.0))
+		Expect(result.Blocks[0].Paragraphs[0].Box).To(Equal(image.Rect(0, 10, 30, 28)))
+		Expect(result.Confidence()).To(Equal(70.0))
+	})
+
+	It("returns the text like tesseract", func() {
+		Expect(result.Text()).To(Equal("The storm\ncame.\n\nIt\n\nNotes\n"))
+	})
+
+	It("translates everything", func() {
+		result.Translate(image.Point{X: 100, Y: 200})
+		Expect(result.Words()[0].Box).To(Equal(image.Rect(100, 210, 110, 218)))
+		Expect(result.Blocks[1].Box).To(Equal(image.Rect(100, 210, 110, 218)))
+	})
+
+	It("maps the boxes of a copy", func() {
+		mapped := result.Mapped(func(r image.Rectangle) image.Rectangle {
+			return image.Rect(r.Min.X*2, r.Min.Y*2, r.Max.X*2, r.Max.Y*2)
+		})
+		Expect(mapped.Words()[1].Box).To(Equal(image.Rect(40, 20, 60, 36)))
+		Expect(mapped.Blocks[0].Paragraphs[0].Lines[0].Box).To(Equal(image.Rect(0, 20, 60, 36)))
+		Expect(mapped.Text()).To(Equal(result.Text()))
+		Expect(result.Words()[1].Box).To(Equal(image.Rect(20, 10, 30, 18)))
+	})
+})
@@ -0,0 +1,49 @@
+package oor
+
+import (
+	"image"
+	"io"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/export"
+	"github.com/jimmykarily/open-ocr-reader/internal/pdf"
+)
+
+// Document returns the text of the result for the exports, in the
+// coordinates of the photo. imageName is the file name of the photo.
+func (r Result) Document(imageName string) export.Document {
+	photo := image.Rectangle{Max: r.Size}
+	doc := export.Document{Image: imageName, Size: r.Size}
+	for _, p := range r.Pages {
+		toOriginal := func(box image.Rectangle) image.Rectangle {
+			return p.ToOriginal.Rect(box).Intersect(photo)
+		}
+		page := export.Page{Box: toOriginal(p.Bounds), Number: p.Number}
+		for _, region := range p.Regions {
+			if region.OCR == nil {
+				continue
+			}
+			if page.Languages == nil {
+				page.Languages = region.OCR.Languages
+			}
+			page.Blocks = append(page.Blocks, region.OCR.Mapped(toOriginal).Blocks...)
+		}
+		doc.Pages = append(doc.Pages, page)
+	}
+
+	return doc
+}
+
+// WritePDF writes the result as a searchable PDF, with a page for every
+// processed page image and the words read from it as invisible text
+func (r Result) WritePDF(w io.Writer) error {
+	pages := []pdf.Page{}
+	for _, p := range r.Pages {
+		page := pdf.Page{Image: p.Image.Object}
+		for _, word := range p.Words() {
+			page.Words = append(page.Words, pdf.Word{Text: word.Text, Box: word.Box})
+		}
+		pages = append(pages, page)
+	}
+
+	return pdf.Write(w, pages, pdf.DefaultDPI)
+}
@@ -0,0 +1,8 @@
+package oor
+
+// The helpers below are exported for the tests of the oor_test package
+var (
+	PageNumber = pageNumber
+	Retry      = retry
+	Score      = score
+)
@@ -4,12 +4,19 @@ package oor
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
 	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
 	"github.com/jimmykarily/open-ocr-reader/internal/logger"
 	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
 	"github.com/jimmykarily/open-ocr-reader/internal/process"
+	"github.com/jimmykarily/open-ocr-reader/internal/reflow"
 	"github.com/jimmykarily/open-ocr-reader/internal/tts"
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
 
 	"github.com/pkg/errors"
 )
@@ -18,15 +25,76 @@ type ParserDeps struct {
 	Processor process.Processor
 	OCR       ocr.OCR
 	TTS       tts.TTS
+	// Selection is the part of the photo to read. The whole photo is read
+	// when empty.
+	Selection process.Selection
+	// Languages are the tesseract languages of the text, for the processing
+	// steps that read it (e.g. to tell the orientation of the photo). They
+	// are detected by the OCR when empty.
+	Languages []string
+	// Pool provides the tesseract clients of the processing steps that read
+	// the text. New clients are created when nil.
+	Pool *ocr.Pool
+	// SpeakMargins reads the headers and the footers of the pages out loud.
+	// They are skipped by default because they repeat on every page.
+	SpeakMargins bool
+	// Variants are the other ways to process and read the photo that are
+	// tried when the mean confidence of the OCR is below RetryBelow (0-100)
+	Variants   []Variant
+	RetryBelow float64
+	// Corrector fixes the words the OCR probably got wrong. Nothing is
+	// corrected when nil.
+	Corrector *ocr.Corrector
+	// DebugDir is where the debug report of each run is written. Debugging
+	// is disabled when empty.
+	DebugDir string
 }
 
-// Parse takes all the steps needed to go from a photo of a book page to audio
-func Parse(imgPath string, deps ParserDeps) error {
+// DebugDirFromEnv returns the debug directory set with the OOR_DEBUG_DIR
+// environment variable
+func DebugDirFromEnv() string {
+	return os.Getenv("OOR_DEBUG_DIR")
+}
+
+// RetryBelowFromEnv returns the confidence set with the OOR_RETRY_BELOW
+// environment variable, below which the variants are tried. It returns 0
+// (never retry) if it is not set.
+func RetryBelowFromEnv() (float64, error) {
+	v := os.Getenv("OOR_RETRY_BELOW")
+	if v == "" {
+		return 0, nil
+	}
+	retryBelow, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "parsing OOR_RETRY_BELOW")
+	}
+
+	return retryBelow, nil
+}
+
+// Parse takes all the steps needed to go from a photo of a book page to audio.
+// It returns what was read, page by page.
+func Parse(imgPath string, deps ParserDeps) (_ *Result, err error) {
 	logger := logger.New()
 
+	rec := debug.New(deps.DebugDir)
+	defer func() {
+		if err != nil {
+			rec.Notef("error", "%+v", err)
+		}
+		if !rec.Enabled() {
+			return
+		}
+		if dir, writeErr := rec.Write(); writeErr != nil {
+			logger.Errorf("writing the debug report: %s", writeErr.Error())
+		} else {
+			logger.Logf("Debug report written to %s", dir)
+		}
+	}()
+
 	textImg, err := img.New(imgPath)
 	if err != nil {
-		return errors.Wrap(err, "reading image file")
+		return nil, errors.Wrap(err, "reading image file")
 	}
 
 	// TODO: It's easy to capture an image with external tools and pass it
@@ -41,15 +109,68 @@ func Parse(imgPath string, deps ParserDeps) error {
 	// }
 
 	logger.Log("Processing the photo...")
-	processedImg, err := deps.Processor.Process(textImg)
+	stopClock := rec.Time("processing")
+	result, err := deps.Processor.Process(textImg, processOptions(deps, rec))
+	stopClock()
 	if err != nil {
-		return errors.Wrap(err, "processing the image")
+		return nil, explain(errors.Wrap(err, "processing the image"), deps)
+	}
+
+	// The quality stage only reports the problems of the photo by default,
+	// the user should still be told how to take a better one
+	for i, page := range result.Pages {
+		if page.Quality != nil && len(page.Quality.Problems) > 0 {
+			rec.Notef("warning", "page %d: %s", i+1, page.Quality.Advice())
+			if err := warn(page.Quality.Advice(), deps); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	for i, page := range result.Pages {
+		if page.FingersOverText() {
+			rec.Notef("warning", "page %d: %s", i+1, process.ProblemFinger.Advice())
+			if err := warn(process.ProblemFinger.Advice(), deps); err != nil {
+				return nil, err
+			}
+			break
+		}
 	}
 
 	logger.Log("Running OCR on the photo...")
-	text, err := deps.OCR.Parse(processedImg)
+	stopClock = rec.Time("ocr")
+	parsed, err := readPages(result, deps.OCR, deps.Corrector)
+	stopClock()
 	if err != nil {
-		return errors.Wrap(err, "running OCR on the image")
+		return nil, err
+	}
+	for i, p := range parsed.Pages {
+		if result.Pages[i].Orientation != 0 {
+			logger.Logf("Page %d was rotated by %d degrees", i+1, result.Pages[i].Orientation)
+		}
+		if p.Number != "" {
+			logger.Logf("Page %d is numbered %s", i+1, p.Number)
+		}
+		rec.Notef("ocr", "page %d: mean confidence %.1f", i+1, p.Confidence())
+	}
+
+	if len(deps.Variants) > 0 && parsed.Confidence() < deps.RetryBelow {
+		logger.Logf("The text is hard to read (confidence %.1f), trying other ways to process the photo...", parsed.Confidence())
+		stopClock = rec.Time("variants")
+		parsed = retry(textImg, parsed, deps, rec)
+		stopClock()
+		logger.Logf("Using the %q variant", parsed.Variant)
+	}
+
+	parsed.Size = textImg.Object.Bounds().Size()
+
+	// The printed line breaks and hyphenation make the speech pause in the
+	// middle of sentences and words
+	text := reflow.Text(parsed.Speech(deps.SpeakMargins), wordlist.ForLanguages(parsed.Languages()))
+	rec.Notef("text", "%s", text)
+	if strings.TrimSpace(text) == "" {
+		return nil, explain(errors.WithStack(process.ErrNoTextFound), deps)
 	}
 
 	fmt.Printf("text = %+v\n", text)
@@ -58,9 +179,116 @@ func Parse(imgPath string, deps ParserDeps) error {
 	// Maybe the tts package can "stream" the audio, as in "play before the whole
 	// text is parsed"?
 	logger.Log("Running text to speech on the photo...")
+	stopClock = rec.Time("tts")
 	err = deps.TTS.Speak(text)
+	stopClock()
 	if err != nil {
-		return errors.Wrap(err, "running text to speech on the text")
+		return nil, errors.Wrap(err, "running text to speech on the text")
+	}
+
+	return parsed, nil
+}
+
+// processOptions returns the options of processing the photo for deps
+func processOptions(deps ParserDeps, rec *debug.Recorder) process.Options {
+	return process.Options{Selection: deps.Selection, Languages: deps.Languages, Pool: deps.Pool, Debug: rec}
+}
+
+// readPages runs OCR on the processed pages and corrects the words with the
+// corrector, if any
+func readPages(result *process.Result, o ocr.OCR, corrector *ocr.Corrector) (*Result, error) {
+	parsed := &Result{Variant: DefaultVariant}
+	for _, page := range result.Pages {
+		p, err := readPage(page, o, corrector)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Pages = append(parsed.Pages, p)
+	}
+
+	return parsed, nil
+}
+
+// readPage runs OCR on the regions of the page one by one, so that columns
+// are read in order and the margins are kept apart from the text
+func readPage(page process.ResultPage, o ocr.OCR, corrector *ocr.Corrector) (Page, error) {
+	p := Page{Image: page.Image, Bounds: page.Image.Object.Bounds(), ToOriginal: page.ToOriginal}
+	regions := page.Regions
+	if len(regions) == 0 {
+		regions = []layout.Region{{Rect: page.Image.Object.Bounds(), Kind: layout.KindText}}
+	}
+
+	texts := []string{}
+	for _, r := range regions {
+		if r.Kind == layout.KindFigure {
+			continue
+		}
+		regionResult, err := o.Recognize(page.Image.Crop(r.Rect))
+		if err != nil {
+			return p, errors.Wrap(err, "running OCR on the image")
+		}
+		// From the coordinates of the region to the coordinates of the page
+		regionResult.Translate(r.Rect.Min)
+		if corrector != nil {
+			corrector.Correct(regionResult)
+		}
+		p.Regions = append(p.Regions, Region{Rect: r.Rect, Kind: r.Kind, OCR: regionResult})
+		regionText := strings.TrimSpace(regionResult.Text())
+
+		// Something that looks like a page number but isn't one is part of
+		// the header or the footer
+		kind := r.Kind
+		if kind == layout.KindPageNumber {
+			if n := pageNumber(regionText); n != "" {
+				p.Number = n
+				continue
+			}
+			kind = layout.KindFooter
+			if r.Rect.Max.Y < page.Image.Object.Bounds().Dy()/2 {
+				kind = layout.KindHeader
+			}
+		}
+
+		switch kind {
+		case layout.KindHeader:
+			p.Header = joinNonEmpty(" ", p.Header, regionText)
+		case layout.KindFooter:
+			p.Footer = joinNonEmpty(" ", p.Footer, regionText)
+		case layout.KindCaption:
+			texts = append(texts, "Figure: "+regionText)
+		default:
+			texts = append(texts, regionText)
+		}
+	}
+	// The regions are in reading order
+	p.Text = joinNonEmpty("\n\n", texts...)
+
+	return p, nil
+}
+
+// explain speaks the explanation of the errors the user can do something
+// about (e.g. take a better photo) instead of reading garbage. It returns the
+// original error.
+func explain(err error, deps ParserDeps) error {
+	explanation, ok := process.Explain(err)
+	if !ok {
+		return err
+	}
+
+	logger.New().Log(explanation)
+	if ttsErr := deps.TTS.Speak(explanation); ttsErr != nil {
+		return errors.Wrap(ttsErr, "running text to speech on the explanation")
+	}
+
+	return err
+}
+
+// warn tells the user about something that affects the text they are about
+// to hear (e.g. a finger covering some of it)
+func warn(warning string, deps ParserDeps) error {
+	logger.New().Log(warning)
+	if err := deps.TTS.Speak(warning); err != nil {
+		return errors.Wrap(err, "running text to speech on the warning")
 	}
 
 	return nil
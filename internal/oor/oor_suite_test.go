@@ -0,0 +1,13 @@
+package oor_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Oor Suite")
+}
@@ -0,0 +1,64 @@
+package oor_test
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/oor"
+	"github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeTTS keeps what it was asked to say
+type fakeTTS struct {
+	spoken *[]string
+}
+
+func (t fakeTTS) Speak(text string) error {
+	*t.spoken = append(*t.spoken, text)
+
+	return nil
+}
+
+var _ = Describe("Parse", func() {
+	var photoPath string
+	var spoken []string
+
+	BeforeEach(func() {
+		photoPath = filepath.Join(GinkgoT().TempDir(), "photo.png")
+		f, err := os.Create(photoPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		Expect(png.Encode(f, image.NewGray(image.Rect(0, 0, 100, 50)))).To(Succeed())
+		spoken = nil
+	})
+
+	deps := func(quality *process.Quality) ParserDeps {
+		return ParserDeps{
+			Processor: fakeProcessor{quality: quality},
+			OCR:       fakeOCR{words: []string{"storm"}, confidence: 90},
+			TTS:       fakeTTS{spoken: &spoken},
+		}
+	}
+
+	It("tells how to take a better photo before reading the text", func() {
+		quality := &process.Quality{Problems: []process.Problem{process.ProblemBlurry, process.ProblemTooDark}}
+		_, err := Parse(photoPath, deps(quality))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(spoken).To(HaveLen(2))
+		Expect(spoken[0]).To(Equal(process.ProblemBlurry.Advice() + " " + process.ProblemTooDark.Advice()))
+		Expect(spoken[1]).To(ContainSubstring("storm"))
+	})
+
+	It("only reads the text when the photo has no problems", func() {
+		_, err := Parse(photoPath, deps(&process.Quality{}))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(spoken).To(HaveLen(1))
+		Expect(spoken[0]).To(ContainSubstring("storm"))
+	})
+})
@@ -0,0 +1,169 @@
+package oor
+
+import (
+	"image"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+)
+
+// Result is what was read from a photo
+type Result struct {
+	// Pages are in reading order
+	Pages []Page
+	// Variant is the name of the variant of the processing that was used
+	Variant string
+	// Size is the width and the height of the photo
+	Size image.Point
+}
+
+// Words returns all the words of all the pages, in reading order
+func (r Result) Words() []ocr.Word {
+	words := []ocr.Word{}
+	for _, p := range r.Pages {
+		words = append(words, p.Words()...)
+	}
+
+	return words
+}
+
+// Languages returns the tesseract languages the text was read with
+func (r Result) Languages() []string {
+	for _, p := range r.Pages {
+		for _, region := range p.Regions {
+			if len(region.OCR.Languages) > 0 {
+				return region.OCR.Languages
+			}
+		}
+	}
+
+	return nil
+}
+
+// Confidence returns the mean confidence of all the words or 0 if there are
+// no words
+func (r Result) Confidence() float64 {
+	return meanConfidence(r.Words())
+}
+
+// Page is what was read from a single page
+type Page struct {
+	// Number is the printed page number (e.g. "12" or "xiv"), if found
+	Number string
+	// Header and Footer are the running heads of the page (e.g. the title of
+	// the chapter)
+	Header string
+	Footer string
+	// Text is the body of the page in reading order, without the header,
+	// the footer and the page number
+	Text string
+	// Regions are all the parts of the page that were OCR'd, in reading
+	// order, including the header, the footer and the page number
+	Regions []Region
+	// Image is the processed page image the text was read from
+	Image *img.Image
+	// Bounds are the bounds of the processed page image
+	Bounds image.Rectangle
+	// ToOriginal maps the coordinates of the processed page image to the
+	// coordinates of the photo
+	ToOriginal geom.Transform
+}
+
+// Region is a part of a page and the text found in it
+type Region struct {
+	// Rect is the region in the coordinates of the processed page image
+	Rect image.Rectangle
+	Kind layout.Kind
+	// OCR has the blocks, paragraphs, lines and words of the region, in
+	// the coordinates of the processed page image
+	OCR *ocr.Result
+}
+
+// Confidence returns the mean confidence of the words of the page or 0 if
+// there are no words
+func (p Page) Confidence() float64 {
+	return meanConfidence(p.Words())
+}
+
+// Words returns all the words of the page, in reading order
+func (p Page) Words() []ocr.Word {
+	words := []ocr.Word{}
+	for _, r := range p.Regions {
+		words = append(words, r.OCR.Words()...)
+	}
+
+	return words
+}
+
+func meanConfidence(words []ocr.Word) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, w := range words {
+		sum += w.Confidence
+	}
+
+	return sum / float64(len(words))
+}
+
+// Speech returns the text to read out loud. The headers and the footers are
+// only included if margins is true.
+func (r Result) Speech(margins bool) string {
+	texts := []string{}
+	for _, p := range r.Pages {
+		if margins {
+			texts = append(texts, p.Header, p.Text, p.Footer)
+		} else {
+			texts = append(texts, p.Text)
+		}
+	}
+
+	return joinNonEmpty("\n\n", texts...)
+}
+
+// romanNumeral matches the Roman numerals below 400, which is more than the
+// front matter of any book has. Letters in the wrong order (e.g. "dim") or
+// words that happen to be made of the same letters (e.g. "civil") don't
+// match.
+var romanNumeral = regexp.MustCompile(`^c{0,3}(xc|xl|l?x{0,3})(ix|iv|v?i{0,3})$`)
+
+// pageNumber returns the page number in the OCR text of a page number region
+// or an empty string if the text isn't a number. Arabic and lower or upper
+// case Roman numerals are accepted.
+func pageNumber(text string) string {
+	// Page numbers are often decorated, e.g. "- 12 -"
+	text = strings.TrimFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if text == "" {
+		return ""
+	}
+
+	if strings.IndexFunc(text, func(r rune) bool { return r < '0' || r > '9' }) < 0 {
+		return text
+	}
+	if (text == strings.ToLower(text) || text == strings.ToUpper(text)) && romanNumeral.MatchString(strings.ToLower(text)) {
+		return text
+	}
+
+	return ""
+}
+
+// joinNonEmpty joins the strings that are not blank
+func joinNonEmpty(sep string, values ...string) string {
+	result := []string{}
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			result = append(result, v)
+		}
+	}
+
+	return strings.Join(result, sep)
+}
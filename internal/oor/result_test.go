@@ -0,0 +1,30 @@
+package oor_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/oor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PageNumber", func() {
+	DescribeTable("accepts page numbers",
+		func(text, expected string) {
+			Expect(PageNumber(text)).To(Equal(expected))
+		},
+		Entry("arabic", "12", "12"),
+		Entry("decorated", "- 12 -", "12"),
+		Entry("lower case roman", "xiv", "xiv"),
+		Entry("upper case roman", "XL", "XL"),
+	)
+
+	DescribeTable("rejects words",
+		func(text string) {
+			Expect(PageNumber(text)).To(BeEmpty())
+		},
+		Entry("made of roman letters", "mid"),
+		Entry("in the wrong order", "dim"),
+		Entry("that look like numerals", "civil"),
+		Entry("of mixed case", "Xiv"),
+		Entry("of other letters", "Chapter"),
+	)
+})
@@ -0,0 +1,205 @@
+package oor
+
+import (
+	"sync"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/jimmykarily/open-ocr-reader/internal/process"
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	"github.com/otiai10/gosseract/v2"
+	"github.com/pkg/errors"
+)
+
+// DefaultVariant is the name of the result of the configured processor and
+// OCR
+const DefaultVariant = "default"
+
+// Variant is another way to process and read a photo, tried when the text
+// of the configured one is hard to read
+type Variant struct {
+	Name      string
+	Processor process.Processor
+	OCR       ocr.OCR
+}
+
+// variantSpecs are the variants tried by default: the configured stages with
+// other thresholds, without deskewing, inverted (light text on a dark
+// background) and with the page segmentation modes of tesseract for a single
+// column and for sparse text (e.g. labels)
+var variantSpecs = []struct {
+	name string
+	// stages returns the stages of the variant from the configured ones or
+	// false if the variant doesn't apply to them
+	stages func([]process.StageSpec) ([]process.StageSpec, bool)
+	psm    gosseract.PageSegMode
+}{
+	{"sauvola", withThreshold("sauvola"), 0},
+	{"adaptive", withThreshold("adaptive"), 0},
+	{"no-deskew", without("deskew"), 0},
+	{"inverted", inverted, 0},
+	{"single-column", unchanged, gosseract.PSM_SINGLE_COLUMN},
+	{"sparse-text", unchanged, gosseract.PSM_SPARSE_TEXT},
+}
+
+// DefaultVariants returns the variants of the stages (see ParseStages) tried
+// by default, reading the text in the given languages with clients of the
+// pool (nil for no pool). The variants that would be the same as the stages
+// are left out.
+func DefaultVariants(stages string, languages []string, pool *ocr.Pool) ([]Variant, error) {
+	specs, err := process.ParseStages(stages)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the stages")
+	}
+
+	variants := []Variant{}
+	for _, spec := range variantSpecs {
+		variantStages, ok := spec.stages(specs)
+		if !ok {
+			continue
+		}
+		p, err := process.NewProcessorFromStages(variantStages)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating the %q variant", spec.name)
+		}
+		variants = append(variants, Variant{
+			Name:      spec.name,
+			Processor: p,
+			OCR:       ocr.TesseractOCR{Languages: languages, PageSegMode: spec.psm, Pool: pool},
+		})
+	}
+
+	return variants, nil
+}
+
+// withThreshold returns the stages with the threshold method changed. It
+// doesn't apply to stages without a threshold or with the same method.
+func withThreshold(method string) func([]process.StageSpec) ([]process.StageSpec, bool) {
+	return func(specs []process.StageSpec) ([]process.StageSpec, bool) {
+		result, changed := []process.StageSpec{}, false
+		for _, s := range specs {
+			if s.Name == "threshold" && s.Params.String("method", "otsu") != method {
+				params := process.Params{}
+				for k, v := range s.Params {
+					params[k] = v
+				}
+				params["method"] = method
+				s = process.StageSpec{Name: s.Name, Params: params}
+				changed = true
+			}
+			result = append(result, s)
+		}
+
+		return result, changed
+	}
+}
+
+// without returns the stages without the named one. It doesn't apply to
+// stages that don't have it.
+func without(name string) func([]process.StageSpec) ([]process.StageSpec, bool) {
+	return func(specs []process.StageSpec) ([]process.StageSpec, bool) {
+		result := []process.StageSpec{}
+		for _, s := range specs {
+			if s.Name != name {
+				result = append(result, s)
+			}
+		}
+
+		return result, len(result) < len(specs)
+	}
+}
+
+// inverted returns the stages with an invert stage after the grayscale one,
+// or first if there is none. It doesn't apply to stages that already invert
+// the image.
+func inverted(specs []process.StageSpec) ([]process.StageSpec, bool) {
+	at := 0
+	for j, s := range specs {
+		switch s.Name {
+		case "invert":
+			return nil, false
+		case "grayscale":
+			at = j + 1
+		}
+	}
+
+	result := append([]process.StageSpec{}, specs[:at]...)
+	result = append(result, process.StageSpec{Name: "invert", Params: process.Params{}})
+
+	return append(result, specs[at:]...), true
+}
+
+// unchanged returns the stages as they are
+func unchanged(specs []process.StageSpec) ([]process.StageSpec, bool) {
+	return specs, true
+}
+
+// retry reads the photo with all the variants in parallel and returns the
+// best result, which can be the one of the default variant
+func retry(textImg *img.Image, best *Result, deps ParserDeps, rec *debug.Recorder) *Result {
+	results := make([]*Result, len(deps.Variants))
+	var wg sync.WaitGroup
+	for i, v := range deps.Variants {
+		wg.Add(1)
+		go func(i int, v Variant) {
+			defer wg.Done()
+			r, err := readVariant(textImg, v, deps)
+			if err != nil {
+				rec.Notef("variants", "%s: %s", v.Name, err.Error())
+				return
+			}
+			results[i] = r
+		}(i, v)
+	}
+	wg.Wait()
+
+	bestScore := score(best)
+	rec.Notef("variants", "%s: score %.3f", best.Variant, bestScore)
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		s := score(r)
+		rec.Notef("variants", "%s: score %.3f", r.Variant, s)
+		if s > bestScore {
+			best, bestScore = r, s
+		}
+	}
+
+	return best
+}
+
+// readVariant processes and reads the photo the way of the variant
+func readVariant(textImg *img.Image, v Variant, deps ParserDeps) (*Result, error) {
+	result, err := v.Processor.Process(textImg, processOptions(deps, nil))
+	if err != nil {
+		return nil, errors.Wrap(err, "processing the image")
+	}
+
+	parsed, err := readPages(result, v.OCR, deps.Corrector)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Variant = v.Name
+
+	return parsed, nil
+}
+
+// score rates a result from 0 to 1 by the confidence of tesseract and, when
+// there is a wordlist for its languages, by the fraction of its words that
+// are real words. Garbage read with confidence scores low on the latter.
+func score(r *Result) float64 {
+	confidence := r.Confidence() / 100
+	words := wordlist.ForLanguages(r.Languages())
+	if words == nil {
+		return confidence
+	}
+
+	texts := []string{}
+	for _, w := range r.Words() {
+		texts = append(texts, w.Text)
+	}
+
+	return (confidence + words.Ratio(texts)) / 2
+}
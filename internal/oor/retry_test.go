@@ -0,0 +1,178 @@
+package oor_test
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	. "github.com/jimmykarily/open-ocr-reader/internal/oor"
+	"github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+// fakeProcessor returns the photo as a single page, with the given quality
+type fakeProcessor struct {
+	err     error
+	quality *process.Quality
+}
+
+func (p fakeProcessor) Process(image *img.Image, opts process.Options) (*process.Result, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return &process.Result{Pages: []process.ResultPage{{Image: image, Quality: p.quality}}}, nil
+}
+
+// fakeOCR reads the given words with the same confidence
+type fakeOCR struct {
+	words      []string
+	confidence float64
+	languages  []string
+}
+
+func (o fakeOCR) Parse(image *img.Image) (string, error) {
+	r, err := o.Recognize(image)
+	if err != nil {
+		return "", err
+	}
+
+	return r.Text(), nil
+}
+
+func (o fakeOCR) Recognize(image *img.Image) (*ocr.Result, error) {
+	words := []ocr.WordBox{}
+	for j, w := range o.words {
+		words = append(words, ocr.WordBox{
+			Word:     ocr.Word{Box: image.Object.Bounds(), Confidence: o.confidence, Text: w},
+			BlockNum: 1, ParNum: 1, LineNum: 1 + j,
+		})
+	}
+	result := ocr.NewResult(words)
+	result.Languages = o.languages
+
+	return result, nil
+}
+
+// read returns the result of reading a page with o
+func read(o fakeOCR) *Result {
+	r, _ := o.Recognize(&img.Image{Object: image.NewGray(image.Rect(0, 0, 10, 10))})
+
+	return &Result{Variant: DefaultVariant, Pages: []Page{{Regions: []Region{{OCR: r}}}}}
+}
+
+var _ = Describe("DefaultVariants", func() {
+	names := func(variants []Variant) []string {
+		result := []string{}
+		for _, v := range variants {
+			result = append(result, v.Name)
+		}
+		return result
+	}
+
+	stages := func(v Variant) []string {
+		result := []string{}
+		for _, s := range v.Processor.(process.PipelineProcessor).Stages {
+			result = append(result, s.Name())
+		}
+		return result
+	}
+
+	It("changes the default stages", func() {
+		variants, err := DefaultVariants(process.DefaultStages, []string{"eng"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(variants)).To(Equal([]string{"sauvola", "adaptive", "no-deskew", "inverted", "single-column", "sparse-text"}))
+		Expect(stages(variants[2])).ToNot(ContainElement("deskew"))
+		Expect(stages(variants[3])[:3]).To(Equal([]string{"quality", "grayscale", "invert"}))
+	})
+
+	It("changes the configured stages", func() {
+		variants, err := DefaultVariants("grayscale,threshold(method=sauvola,block=31),border", nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		// There is nothing to deskew and the threshold is already sauvola
+		Expect(names(variants)).To(Equal([]string{"adaptive", "inverted", "single-column", "sparse-text"}))
+
+		threshold := variants[0].Processor.(process.PipelineProcessor).Stages[1].(process.ThresholdStage)
+		Expect(threshold.Method).To(Equal("adaptive"))
+		Expect(threshold.BlockSize).To(Equal(31))
+		Expect(stages(variants[1])).To(Equal([]string{"grayscale", "invert", "threshold", "border"}))
+	})
+
+	It("returns an error for invalid stages", func() {
+		_, err := DefaultVariants("threshold(method=sauvola", nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Retry", func() {
+	var photo *img.Image
+
+	BeforeEach(func() {
+		photo = &img.Image{Object: image.NewGray(image.Rect(0, 0, 100, 50))}
+	})
+
+	variant := func(name string, confidence float64) Variant {
+		return Variant{Name: name, Processor: fakeProcessor{}, OCR: fakeOCR{words: []string{"storm"}, confidence: confidence}}
+	}
+
+	It("returns the result of the best variant", func() {
+		deps := ParserDeps{Variants: []Variant{
+			variant("worse", 30),
+			variant("better", 80),
+			{Name: "broken", Processor: fakeProcessor{err: errors.New("no page")}, OCR: fakeOCR{}},
+		}}
+
+		best := Retry(photo, read(fakeOCR{words: []string{"storm"}, confidence: 40}), deps, nil)
+		Expect(best.Variant).To(Equal("better"))
+		Expect(best.Confidence()).To(Equal(80.0))
+	})
+
+	It("keeps the default result when no variant is better", func() {
+		deps := ParserDeps{Variants: []Variant{variant("worse", 30), variant("same", 40)}}
+
+		best := Retry(photo, read(fakeOCR{words: []string{"storm"}, confidence: 40}), deps, nil)
+		Expect(best.Variant).To(Equal(DefaultVariant))
+	})
+})
+
+var _ = Describe("Score", func() {
+	It("is the confidence when there is no wordlist", func() {
+		Expect(Score(read(fakeOCR{words: []string{"storm", "xqzv"}, confidence: 80}))).To(BeNumerically("~", 0.8, 0.001))
+	})
+
+	It("is lower when the words are not real words", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "tst.txt"), []byte("storm\n"), 0644)).To(Succeed())
+		os.Setenv("OOR_WORDLIST_DIR", dir)
+		DeferCleanup(os.Unsetenv, "OOR_WORDLIST_DIR")
+
+		r := read(fakeOCR{words: []string{"storm", "xqzv"}, confidence: 80, languages: []string{"tst"}})
+		Expect(Score(r)).To(BeNumerically("~", (0.8+0.5)/2, 0.001))
+	})
+})
+
+var _ = Describe("RetryBelowFromEnv", func() {
+	AfterEach(func() {
+		os.Unsetenv("OOR_RETRY_BELOW")
+	})
+
+	It("never retries by default", func() {
+		os.Unsetenv("OOR_RETRY_BELOW")
+		Expect(RetryBelowFromEnv()).To(BeZero())
+	})
+
+	It("parses the confidence", func() {
+		os.Setenv("OOR_RETRY_BELOW", "65.5")
+		Expect(RetryBelowFromEnv()).To(Equal(65.5))
+	})
+
+	It("returns an error for invalid values", func() {
+		os.Setenv("OOR_RETRY_BELOW", "high")
+		_, err := RetryBelowFromEnv()
+		Expect(err).To(MatchError(ContainSubstring("OOR_RETRY_BELOW")))
+	})
+})
@@ -0,0 +1,256 @@
+// Package pdf is responsible for writing searchable PDF files: the image of
+// every page with an invisible layer of text on top, placed where the words
+// are on the image. Viewers show the image but the text can be searched,
+// selected and read by screen readers.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultDPI is the resolution the pages are assumed to have when none is
+// given. It sets the size of the PDF pages.
+const DefaultDPI = 300
+
+// glyphWidth is the width of every glyph of the font, as a fraction of the
+// font size. Using a font with a single width makes it easy to stretch the
+// words to the width of their boxes.
+const glyphWidth = 0.5
+
+// Page is an image and the words found in it
+type Page struct {
+	Image image.Image
+	// Words are in the coordinates of the image
+	Words []Word
+}
+
+// Word is a word and its box on the image
+type Word struct {
+	Text string
+	Box  image.Rectangle
+}
+
+// Write writes the pages as a PDF file. dpi is the resolution of the images
+// (DefaultDPI if 0). The text is written with two byte codes and a ToUnicode
+// map, so that any script (e.g. Greek) can be searched and copied.
+func Write(w io.Writer, pages []Page, dpi float64) error {
+	if len(pages) == 0 {
+		return errors.New("no pages to write")
+	}
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+
+	d := &document{}
+	d.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	codes, err := newEncoding(pages)
+	if err != nil {
+		return err
+	}
+
+	// The catalog, the page tree and the four objects of the font come
+	// first and the pages after them, three objects per page
+	const catalog, tree, font, cidFont, descriptor, toUnicode = 1, 2, 3, 4, 5, 6
+	kids := []string{}
+	for j := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 7+3*j))
+	}
+	d.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", tree))
+	d.object(tree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	d.object(font, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /GlyphLessFont /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		cidFont, toUnicode))
+	// The text is invisible so the font has no glyphs to embed, only
+	// their width
+	d.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /GlyphLessFont /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW %d /CIDToGIDMap /Identity >>",
+		descriptor, int(glyphWidth*1000)))
+	d.object(descriptor, "<< /Type /FontDescriptor /FontName /GlyphLessFont /Flags 5 /FontBBox [0 0 500 1000] /ItalicAngle 0 /Ascent 1000 /Descent 0 /CapHeight 1000 /StemV 80 >>")
+	d.stream(toUnicode, "", []byte(codes.cmap()))
+
+	for j, p := range pages {
+		page, contents, img := 7+3*j, 8+3*j, 9+3*j
+		size := p.Image.Bounds().Size()
+		width, height := float64(size.X)*72/dpi, float64(size.Y)*72/dpi
+
+		d.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> /XObject << /Im1 %d 0 R >> >> /Contents %d 0 R >>",
+			tree, num(width), num(height), font, img, contents))
+		d.stream(contents, "", []byte(content(p, codes, width, height, 72/dpi)))
+
+		data, colorSpace, err := encodeImage(p.Image)
+		if err != nil {
+			return errors.Wrapf(err, "encoding the image of page %d", j+1)
+		}
+		d.stream(img, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			size.X, size.Y, colorSpace), data)
+	}
+
+	d.trailer(catalog)
+	_, err = w.Write(d.buf.Bytes())
+
+	return errors.Wrap(err, "writing the PDF")
+}
+
+// document keeps the offsets of the objects for the cross-reference table
+type document struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (d *document) object(n int, body string) {
+	d.begin(n)
+	d.buf.WriteString(body + "\nendobj\n")
+}
+
+func (d *document) stream(n int, dict string, data []byte) {
+	d.begin(n)
+	fmt.Fprintf(&d.buf, "<< %s /Length %d >>\nstream\n", strings.TrimSpace(dict), len(data))
+	d.buf.Write(data)
+	d.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// begin starts object n. The objects have to be written in order.
+func (d *document) begin(n int) {
+	d.offsets = append(d.offsets, d.buf.Len())
+	fmt.Fprintf(&d.buf, "%d 0 obj\n", n)
+}
+
+func (d *document) trailer(root int) {
+	xref := d.buf.Len()
+	fmt.Fprintf(&d.buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, offset := range d.offsets {
+		fmt.Fprintf(&d.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&d.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, root, xref)
+}
+
+// content returns the drawing operators of a page: the image over the whole
+// page and the invisible text (rendering mode 3) on top of it
+func content(p Page, codes encoding, width, height, scale float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "q %s 0 0 %s 0 0 cm /Im1 Do Q\n", num(width), num(height))
+	fmt.Fprintf(&b, "BT\n3 Tr\n")
+	bounds := p.Image.Bounds()
+	for _, w := range p.Words {
+		text := codes.encode(w.Text)
+		if text == "" || w.Box.Empty() {
+			continue
+		}
+		size := float64(w.Box.Dy()) * scale
+		x := float64(w.Box.Min.X-bounds.Min.X) * scale
+		// PDF coordinates start at the bottom
+		y := float64(bounds.Max.Y-w.Box.Max.Y) * scale
+		// Stretch the word to the width of its box
+		stretch := 100 * float64(w.Box.Dx()) * scale / (float64(len(text)/4) * glyphWidth * size)
+		fmt.Fprintf(&b, "/F1 %s Tf %s Tz 1 0 0 1 %s %s Tm <%s> Tj\n", num(size), num(stretch), num(x), num(y), text)
+	}
+	b.WriteString("ET\n")
+
+	return b.String()
+}
+
+// encodeImage returns the image as JPEG and its PDF color space
+func encodeImage(img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, "", err
+	}
+	// The JPEG encoder writes grayscale images with a single component
+	if _, ok := img.(*image.Gray); ok {
+		return buf.Bytes(), "/DeviceGray", nil
+	}
+
+	return buf.Bytes(), "/DeviceRGB", nil
+}
+
+// encoding gives every character of the document a two byte code, in the
+// order they are first found
+type encoding map[rune]int
+
+// maxCodes is the number of two byte codes, without code 0
+const maxCodes = 0xffff
+
+func newEncoding(pages []Page) (encoding, error) {
+	codes := encoding{}
+	for _, p := range pages {
+		for _, w := range p.Words {
+			for _, r := range w.Text {
+				if _, ok := codes[r]; ok || !printable(r) {
+					continue
+				}
+				if len(codes) == maxCodes {
+					return nil, errors.New("too many different characters for the PDF font")
+				}
+				codes[r] = len(codes) + 1
+			}
+		}
+	}
+
+	return codes, nil
+}
+
+// encode returns the codes of the text in hexadecimal, four digits per
+// character
+func (e encoding) encode(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if code, ok := e[r]; ok {
+			fmt.Fprintf(&b, "%04X", code)
+		}
+	}
+
+	return b.String()
+}
+
+// cmap returns the ToUnicode CMap that maps the codes back to the text
+func (e encoding) cmap() string {
+	runes := make([]rune, len(e)+1)
+	for r, code := range e {
+		runes[code] = r
+	}
+
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// A bfchar section can have at most 100 entries
+	for start := 1; start < len(runes); start += 100 {
+		end := start + 100
+		if end > len(runes) {
+			end = len(runes)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for code := start; code < end; code++ {
+			fmt.Fprintf(&b, "<%04X> <", code)
+			for _, u := range utf16.Encode([]rune{runes[code]}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	return b.String()
+}
+
+// printable reports whether the character can be written in the text layer
+func printable(r rune) bool {
+	return r != unicode.ReplacementChar && unicode.IsGraphic(r)
+}
+
+// num formats a number for PDF, with at most two decimals
+func num(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", v), "0")
+
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,87 @@
+package pdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/pdf"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write", func() {
+	var pages []Page
+
+	BeforeEach(func() {
+		pages = []Page{
+			{
+				Image: image.NewGray(image.Rect(0, 0, 600, 300)),
+				Words: []Word{
+					{Text: "Fish", Box: image.Rect(100, 50, 220, 80)},
+					{Text: "(café)", Box: image.Rect(240, 50, 420, 80)},
+				},
+			},
+			{Image: image.NewRGBA(image.Rect(0, 0, 300, 600))},
+		}
+	})
+
+	It("writes a page per image with the invisible text", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, pages, 150)).To(Succeed())
+		out := buf.String()
+
+		Expect(out).To(HavePrefix("%PDF-1.4"))
+		Expect(out).To(HaveSuffix("%%EOF\n"))
+		Expect(out).To(ContainSubstring("/Count 2"))
+		Expect(out).To(ContainSubstring("/MediaBox [0 0 288 144]"))
+		Expect(out).To(ContainSubstring("/MediaBox [0 0 144 288]"))
+		Expect(out).To(ContainSubstring("/ColorSpace /DeviceGray"))
+		Expect(out).To(ContainSubstring("/ColorSpace /DeviceRGB"))
+		Expect(out).To(ContainSubstring("3 Tr"))
+		// 30 pixels high at 150 DPI, 120 pixels wide for 4 letters
+		Expect(out).To(ContainSubstring("/F1 14.4 Tf 200 Tz 1 0 0 1 48 105.6 Tm <0001000200030004> Tj"))
+		Expect(out).To(ContainSubstring("<00050006000700080009000A> Tj"))
+	})
+
+	It("maps the codes back to the text", func() {
+		pages[1].Words = []Word{{Text: "ψάρι 🐟", Box: image.Rect(10, 10, 100, 40)}}
+		var buf bytes.Buffer
+		Expect(Write(&buf, pages, 0)).To(Succeed())
+		out := buf.String()
+
+		Expect(out).To(ContainSubstring("/Subtype /Type0 /BaseFont /GlyphLessFont /Encoding /Identity-H"))
+		Expect(out).To(ContainSubstring("/CMapName /Adobe-Identity-UCS def"))
+		Expect(out).To(ContainSubstring("16 beginbfchar\n<0001> <0046>\n"))
+		Expect(out).To(ContainSubstring("<0005> <0028>\n"))
+		Expect(out).To(ContainSubstring("<0009> <00E9>\n<000A> <0029>\n"))
+		Expect(out).To(ContainSubstring("<000B> <03C8>\n<000C> <03AC>\n<000D> <03C1>\n<000E> <03B9>\n<000F> <0020>\n<0010> <D83DDC1F>\nendbfchar"))
+		Expect(out).To(ContainSubstring("<000B000C000D000E000F0010> Tj"))
+	})
+
+	It("points the cross-reference table to the objects", func() {
+		var buf bytes.Buffer
+		Expect(Write(&buf, pages, 0)).To(Succeed())
+		out := buf.Bytes()
+
+		start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+		Expect(start).ToNot(BeNil())
+		xref, err := strconv.Atoi(string(start[1]))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(out[xref:])).To(HavePrefix("xref\n0 13\n"))
+
+		offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(out, -1)
+		Expect(offsets).To(HaveLen(12))
+		for j, o := range offsets {
+			offset, err := strconv.Atoi(string(o[1]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out[offset:])).To(HavePrefix(fmt.Sprintf("%d 0 obj", j+1)))
+		}
+	})
+
+	It("fails without pages", func() {
+		Expect(Write(&bytes.Buffer{}, nil, 0)).ToNot(Succeed())
+	})
+})
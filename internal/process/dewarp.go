@@ -0,0 +1,266 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+	"sort"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("dewarp", newDewarpStage)
+}
+
+// DewarpStage straightens the text lines of a page that curve near the spine
+// of a thick book. It fits a polynomial on every text line and remaps the
+// image so that the fitted curves become straight horizontal lines. The
+// remapping can't be undone exactly by a Transform, so ToOriginal gets the
+// projective transform closest to it: the boxes of the words mapped back to
+// the photo may still be off by a few pixels where the lines curve most.
+type DewarpStage struct {
+	// Degree is the degree of the polynomial fitted on each line
+	Degree int
+	// MinLines is the minimum number of text lines needed to dewarp the page.
+	// With fewer lines the page is left untouched.
+	MinLines int
+}
+
+func newDewarpStage(params Params) (Stage, error) {
+	degree, err := params.Int("degree", 2)
+	if err != nil {
+		return nil, err
+	}
+	if degree < 1 || degree > 5 {
+		return nil, errors.New("degree should be between 1 and 5")
+	}
+	minLines, err := params.Int("min-lines", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	return DewarpStage{Degree: degree, MinLines: minLines}, nil
+}
+
+func (s DewarpStage) Name() string { return "dewarp" }
+
+func (s DewarpStage) Apply(page *Page) error {
+	lines := findTextLines(page.Debug, page.Mat, s.Degree)
+	page.Debug.Notef("dewarp", "%d text lines found", len(lines))
+	if len(lines) < s.MinLines {
+		return nil
+	}
+
+	// Debug
+	curvesCopy := page.Mat.Clone()
+	defer curvesCopy.Close()
+	drawTextLines(&curvesCopy, lines)
+	storeDebug(page.Debug, &curvesCopy, "dewarp-before")
+
+	dewarped, moved, err := dewarp(page.Mat, lines)
+	if err != nil {
+		return err
+	}
+	page.Mat.Close()
+	page.Mat = dewarped
+	page.moved(moved)
+	storeDebug(page.Debug, &page.Mat, "dewarp-after")
+
+	return nil
+}
+
+// textLine is a line of text as a curve. The curve gives the vertical
+// position of the center of the line as a function of the (normalized)
+// horizontal position.
+type textLine struct {
+	curve      polynomial
+	minX, maxX int
+	// y is where the line ends up after the dewarping
+	y float64
+}
+
+// offset returns how far the line is from its straight position at column x
+func (l textLine) offset(x, cols int) float64 {
+	return l.curve.at(normalizeX(clamp(x, l.minX, l.maxX), cols)) - l.y
+}
+
+// findTextLines finds the long lines of text in the image and fits a curve
+// on each one of them. The lines are sorted from top to bottom.
+func findTextLines(rec *debug.Recorder, i gocv.Mat, degree int) []textLine {
+	mask := i.Clone()
+	defer mask.Close()
+	if mask.Channels() > 1 {
+		convertToGrayscale(&mask)
+	}
+	gocv.AdaptiveThreshold(mask, &mask, 255, gocv.AdaptiveThresholdGaussian, gocv.ThresholdBinaryInv, 25, 15)
+
+	// Join the characters of each line but not the lines with each other
+	kernelWidth := i.Cols() / 50
+	if m, ok := EstimateTextMetrics(i); ok {
+		kernelWidth = 2 * m.GlyphWidth
+	}
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, goimage.Point{X: max(kernelWidth, 3), Y: 1})
+	defer kernel.Close()
+	gocv.Dilate(mask, &mask, kernel)
+	storeDebug(rec, &mask, "dewarp-lines")
+
+	contours := gocv.FindContours(mask, gocv.RetrievalExternal, gocv.ChainApproxNone)
+	defer contours.Close()
+
+	lines := []textLine{}
+	for j := 0; j < contours.Size(); j++ {
+		rect := gocv.BoundingRect(contours.At(j))
+		if rect.Dx() < i.Cols()/4 || rect.Dx() < 5*rect.Dy() {
+			continue
+		}
+
+		// The center line is half way between the top and the bottom of the contour
+		top, bottom := map[int]int{}, map[int]int{}
+		for _, p := range contours.At(j).ToPoints() {
+			if y, ok := top[p.X]; !ok || p.Y < y {
+				top[p.X] = p.Y
+			}
+			if y, ok := bottom[p.X]; !ok || p.Y > y {
+				bottom[p.X] = p.Y
+			}
+		}
+		xs, ys := []float64{}, []float64{}
+		for x, y := range top {
+			xs = append(xs, normalizeX(x, i.Cols()))
+			ys = append(ys, float64(y+bottom[x])/2)
+		}
+
+		curve := fitPolynomial(xs, ys, degree)
+		if curve == nil {
+			continue
+		}
+		line := textLine{curve: curve, minX: rect.Min.X, maxX: rect.Max.X - 1}
+		for x := line.minX; x <= line.maxX; x++ {
+			line.y += curve.at(normalizeX(x, i.Cols()))
+		}
+		line.y /= float64(line.maxX - line.minX + 1)
+		lines = append(lines, line)
+	}
+
+	sort.Slice(lines, func(a, b int) bool { return lines[a].y < lines[b].y })
+
+	return lines
+}
+
+// dewarp remaps the image so that every line ends up straight. The offset of
+// pixels between two lines is interpolated from the offsets of the lines. It
+// returns approximately how the pixels were moved.
+func dewarp(i gocv.Mat, lines []textLine) (gocv.Mat, geom.Transform, error) {
+	rows, cols := i.Rows(), i.Cols()
+	mapX := gocv.NewMatWithSize(rows, cols, gocv.MatTypeCV32FC1)
+	defer mapX.Close()
+	mapY := gocv.NewMatWithSize(rows, cols, gocv.MatTypeCV32FC1)
+	defer mapY.Close()
+
+	xData, err := mapX.DataPtrFloat32()
+	if err != nil {
+		return gocv.Mat{}, geom.Transform{}, errors.Wrap(err, "accessing the x map")
+	}
+	yData, err := mapY.DataPtrFloat32()
+	if err != nil {
+		return gocv.Mat{}, geom.Transform{}, errors.Wrap(err, "accessing the y map")
+	}
+
+	// A grid of the moved pixels, to fit the transform on
+	stepX, stepY := max(cols/20, 1), max(rows/20, 1)
+	from, to := []gocv.Point2f{}, []gocv.Point2f{}
+
+	offsets := make([]float64, len(lines))
+	for x := 0; x < cols; x++ {
+		for k, l := range lines {
+			offsets[k] = l.offset(x, cols)
+		}
+
+		k := 0
+		for y := 0; y < rows; y++ {
+			for k < len(lines) && lines[k].y < float64(y) {
+				k++
+			}
+
+			var offset float64
+			switch {
+			case k == 0:
+				offset = offsets[0]
+			case k == len(lines):
+				offset = offsets[len(lines)-1]
+			default:
+				above, below := lines[k-1].y, lines[k].y
+				t := (float64(y) - above) / (below - above)
+				offset = offsets[k-1]*(1-t) + offsets[k]*t
+			}
+
+			xData[y*cols+x] = float32(x)
+			yData[y*cols+x] = float32(float64(y) + offset)
+			if x%stepX == 0 && y%stepY == 0 {
+				from = append(from, gocv.Point2f{X: float32(x), Y: float32(float64(y) + offset)})
+				to = append(to, gocv.Point2f{X: float32(x), Y: float32(y)})
+			}
+		}
+	}
+
+	result := gocv.NewMat()
+	gocv.Remap(i, &result, &mapX, &mapY, gocv.InterpolationCubic, gocv.BorderReplicate, color.RGBA{})
+
+	return result, fitTransform(from, to), nil
+}
+
+// fitTransform returns the projective transform that maps the points from
+// to the points to with the least squared error. It returns the Identity if
+// there is none.
+func fitTransform(from, to []gocv.Point2f) geom.Transform {
+	if len(from) < 4 {
+		return geom.Identity()
+	}
+	fromMat, toMat := pointsMat(from), pointsMat(to)
+	defer fromMat.Close()
+	defer toMat.Close()
+	mask := gocv.NewMat()
+	defer mask.Close()
+
+	m := gocv.FindHomography(fromMat, &toMat, gocv.HomograpyMethodAllPoints, 3, &mask, 2000, 0.995)
+	defer m.Close()
+	if m.Empty() {
+		return geom.Identity()
+	}
+
+	return matTransform(m)
+}
+
+// pointsMat returns the points as a Mat with a row per point
+func pointsMat(points []gocv.Point2f) gocv.Mat {
+	m := gocv.NewMatWithSize(len(points), 2, gocv.MatTypeCV64F)
+	for j, p := range points {
+		m.SetDoubleAt(j, 0, float64(p.X))
+		m.SetDoubleAt(j, 1, float64(p.Y))
+	}
+
+	return m
+}
+
+func drawTextLines(i *gocv.Mat, lines []textLine) {
+	for _, l := range lines {
+		for x := l.minX; x < l.maxX; x++ {
+			from := goimage.Point{X: x, Y: int(l.curve.at(normalizeX(x, i.Cols())))}
+			to := goimage.Point{X: x + 1, Y: int(l.curve.at(normalizeX(x+1, i.Cols())))}
+			gocv.Line(i, from, to, color.RGBA{255, 0, 0, 255}, 2)
+		}
+	}
+}
+
+// normalizeX maps x from [0, cols) to [-1, 1) to keep the polynomial fit
+// numerically stable
+func normalizeX(x, cols int) float64 {
+	return 2*float64(x)/float64(cols) - 1
+}
+
+func clamp(v, low, high int) int {
+	return min(max(v, low), high)
+}
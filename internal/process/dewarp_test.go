@@ -0,0 +1,42 @@
+package process_test
+
+import (
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+var _ = Describe("FitTransform", func() {
+	It("fits the moved points", func() {
+		from, to := []gocv.Point2f{}, []gocv.Point2f{}
+		for x := 0; x <= 100; x += 20 {
+			for y := 0; y <= 100; y += 20 {
+				from = append(from, gocv.Point2f{X: float32(x), Y: float32(y)})
+				to = append(to, gocv.Point2f{X: float32(x), Y: float32(y) - 5})
+			}
+		}
+
+		x, y := FitTransform(from, to).Apply(50, 50)
+		Expect(x).To(BeNumerically("~", 50, 0.01))
+		Expect(y).To(BeNumerically("~", 45, 0.01))
+	})
+
+	It("returns the Identity without enough points", func() {
+		Expect(FitTransform(nil, nil)).To(Equal(geom.Identity()))
+	})
+})
+
+var _ = Describe("DewarpStage", func() {
+	It("barely moves straight lines", func() {
+		m := textPage(230, 100)
+		page := &Page{Mat: m, ToOriginal: geom.Identity()}
+		defer func() { page.Mat.Close() }()
+
+		Expect(DewarpStage{Degree: 2, MinLines: 3}.Apply(page)).To(Succeed())
+		x, y := page.ToOriginal.Apply(500, 700)
+		Expect(x).To(BeNumerically("~", 500, 2))
+		Expect(y).To(BeNumerically("~", 700, 2))
+	})
+})
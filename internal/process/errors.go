@@ -0,0 +1,42 @@
+package process
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrEmptyImage is returned when the image has no pixels (e.g. it
+	// couldn't be decoded)
+	ErrEmptyImage = errors.New("the image is empty")
+	// ErrImageTooSmall is returned when the image is too small to contain
+	// readable text
+	ErrImageTooSmall = errors.New("the image is too small")
+	// ErrNoTextFound is returned when there is no text in the image
+	ErrNoTextFound = errors.New("no text found in the image")
+	// ErrInvalidSelection is returned when the selected part of the image
+	// is outside of the image
+	ErrInvalidSelection = errors.New("the selection is outside of the image")
+)
+
+var explanations = map[error]string{
+	ErrEmptyImage:       "The photo could not be read. Please take another one.",
+	ErrImageTooSmall:    "The photo is too small. Please use a higher resolution.",
+	ErrNoTextFound:      "No text was found in the photo. Make sure the page is in front of the camera.",
+	ErrInvalidSelection: "The selected area is outside of the photo. Please select the text to read again.",
+}
+
+// Explain returns an explanation of the error that can be spoken or shown to
+// the user, telling them what went wrong and what to do about it. It returns
+// false for errors that the user can't do anything about.
+func Explain(err error) (string, bool) {
+	if qErr, ok := IsQualityError(err); ok {
+		return qErr.Quality.Advice(), true
+	}
+	for e, explanation := range explanations {
+		if errors.Is(err, e) {
+			return explanation, true
+		}
+	}
+
+	return "", false
+}
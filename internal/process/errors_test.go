@@ -0,0 +1,27 @@
+package process_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Explain", func() {
+	It("explains wrapped errors", func() {
+		explanation, ok := Explain(errors.Wrap(ErrNoTextFound, "processing the image"))
+		Expect(ok).To(BeTrue())
+		Expect(explanation).To(ContainSubstring("No text was found"))
+	})
+
+	It("explains quality errors", func() {
+		explanation, ok := Explain(&QualityError{Quality: Quality{Problems: []Problem{ProblemBlurry}}})
+		Expect(ok).To(BeTrue())
+		Expect(explanation).To(Equal(ProblemBlurry.Advice()))
+	})
+
+	It("doesn't explain other errors", func() {
+		_, ok := Explain(errors.New("connection refused"))
+		Expect(ok).To(BeFalse())
+	})
+})
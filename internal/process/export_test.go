@@ -0,0 +1,14 @@
+package process
+
+// The helpers below are exported for the tests of the process_test package
+var (
+	OrderCorners  = orderCorners
+	FitPolynomial = fitPolynomial
+	Solve         = solve
+	FitTransform  = fitTransform
+)
+
+// Scale exports UpscaleStage.scale
+func (s UpscaleStage) Scale(glyphHeight, size int) float64 {
+	return s.scale(glyphHeight, size)
+}
@@ -0,0 +1,130 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("figures", newFiguresStage)
+}
+
+// FiguresStage finds the regions of the page that are not text (photos,
+// diagrams, decorations) and paints them white so that tesseract doesn't
+// turn them into junk characters. The text right next to a figure is marked
+// as its caption. It uses the regions found by the layout stage or segments
+// the page itself if there are none. It needs the grayscale image so it
+// should run before the threshold stage.
+type FiguresStage struct {
+	// MaxMidtones is the maximum fraction of gray (neither black nor white)
+	// pixels in a text region. Photos have a lot of them.
+	MaxMidtones float64
+	// MinGlyphInk is the minimum fraction of the ink of a text region that
+	// belongs to character sized shapes
+	MinGlyphInk float64
+	RightToLeft bool
+}
+
+func newFiguresStage(params Params) (Stage, error) {
+	var err error
+	s := FiguresStage{}
+	if s.MaxMidtones, err = params.Float("max-midtones", 0.3); err != nil {
+		return nil, err
+	}
+	if s.MinGlyphInk, err = params.Float("min-glyph-ink", 0.5); err != nil {
+		return nil, err
+	}
+	direction := params.String("direction", "ltr")
+	if direction != "ltr" && direction != "rtl" {
+		return nil, errors.Errorf("direction should be ltr or rtl, got %q", direction)
+	}
+	s.RightToLeft = direction == "rtl"
+
+	return s, nil
+}
+
+func (s FiguresStage) Name() string { return "figures" }
+
+func (s FiguresStage) Apply(page *Page) error {
+	m, ok := EstimateTextMetrics(page.Mat)
+	if !ok {
+		return nil
+	}
+
+	if len(page.Regions) == 0 {
+		regions, err := segmentPage(page.Debug, page.Mat, s.RightToLeft)
+		if err != nil {
+			return err
+		}
+		page.Regions = regions
+	}
+
+	gray := page.Mat.Clone()
+	defer gray.Close()
+	if gray.Channels() > 1 {
+		convertToGrayscale(&gray)
+	}
+	text := gocv.NewMat()
+	defer text.Close()
+	gocv.AdaptiveThreshold(gray, &text, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(gray), 15)
+
+	for j, r := range page.Regions {
+		if r.Kind == layout.KindText && s.isFigure(gray, text, r.Rect, m) {
+			page.Regions[j].Kind = layout.KindFigure
+		}
+	}
+	layout.MarkCaptions(page.Regions, 2*m.LineSpacing, 3*m.LineSpacing)
+	page.Debug.Notef("figures", "regions: %+v", page.Regions)
+
+	for _, r := range page.Regions {
+		if r.Kind == layout.KindFigure {
+			gocv.Rectangle(&page.Mat, r.Rect, color.RGBA{255, 255, 255, 255}, -1)
+		}
+	}
+	storeDebug(page.Debug, &page.Mat, "figures-masked")
+
+	return nil
+}
+
+// isFigure tells a figure from a block of text. Photos have many gray pixels
+// and diagrams or decorations have shapes that are much bigger (or smaller)
+// than the characters.
+func (s FiguresStage) isFigure(gray, text gocv.Mat, r goimage.Rectangle, m TextMetrics) bool {
+	area := float64(r.Dx() * r.Dy())
+
+	grayRegion := gray.Region(r)
+	defer grayRegion.Close()
+	midtones := gocv.NewMat()
+	defer midtones.Close()
+	gocv.InRangeWithScalar(grayRegion, gocv.NewScalar(64, 0, 0, 0), gocv.NewScalar(192, 0, 0, 0), &midtones)
+	if float64(gocv.CountNonZero(midtones))/area > s.MaxMidtones {
+		return true
+	}
+
+	textRegion := text.Region(r)
+	defer textRegion.Close()
+	labels, stats, centroids := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer labels.Close()
+	defer stats.Close()
+	defer centroids.Close()
+	n := gocv.ConnectedComponentsWithStats(textRegion, &labels, &stats, &centroids)
+
+	ink, glyphInk := 0, 0
+	for label := 1; label < n; label++ {
+		h := int(stats.GetIntAt(label, int(gocv.CC_STAT_HEIGHT)))
+		a := int(stats.GetIntAt(label, int(gocv.CC_STAT_AREA)))
+		ink += a
+		if h >= m.GlyphHeight/3 && h <= 2*m.GlyphHeight {
+			glyphInk += a
+		}
+	}
+	if ink == 0 {
+		return false
+	}
+
+	return float64(glyphInk)/float64(ink) < s.MinGlyphInk
+}
@@ -0,0 +1,144 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("fingers", newFingersStage)
+}
+
+// Finger is a skin colored blob that enters the photo from its border, most
+// likely the thumb or a finger of the hand holding the book open.
+type Finger struct {
+	// Rect is the bounding rectangle of the finger in the coordinates of the
+	// photo
+	Rect goimage.Rectangle
+	// CoversText is true when the finger lies over the text and not just
+	// over the margins
+	CoversText bool
+}
+
+// FingersStage finds fingers holding the book and paints them white, so that
+// they don't end up as the biggest contour in deskew or as junk characters
+// in OCR. It needs the color image so it should run before the grayscale
+// stage. The fingers found are stored in the page. It is a PhotoStage: the
+// fingers touch the edge of the photo, not the edge of the selection.
+type FingersStage struct {
+	// MinArea is the minimum area of a finger as a fraction of the image
+	// area. Smaller skin colored blobs are ignored.
+	MinArea float64
+}
+
+func newFingersStage(params Params) (Stage, error) {
+	minArea, err := params.Float("min-area", 0.002)
+	if err != nil {
+		return nil, err
+	}
+	if minArea <= 0 || minArea > 1 {
+		return nil, errors.New("min-area should be between 0 and 1")
+	}
+
+	return FingersStage{MinArea: minArea}, nil
+}
+
+func (s FingersStage) Name() string { return "fingers" }
+
+// WholePhoto makes the pipeline look for the fingers on the whole photo, so
+// that skin colored blobs cut by the selection are not taken for fingers
+func (s FingersStage) WholePhoto() {}
+
+func (s FingersStage) Apply(page *Page) error {
+	// No color, no skin
+	if page.Mat.Channels() < 3 {
+		return nil
+	}
+
+	contours := findFingers(page.Debug, page.Mat, s.MinArea)
+	defer contours.Close()
+	if contours.Size() == 0 {
+		return nil
+	}
+
+	// Paint the fingers (and their shadowed outline) white, like the page
+	white := color.RGBA{255, 255, 255, 255}
+	outline := max(page.Mat.Cols()/200, 2)
+	for j := 0; j < contours.Size(); j++ {
+		gocv.DrawContours(&page.Mat, contours, j, white, -1)
+		gocv.DrawContours(&page.Mat, contours, j, white, outline)
+	}
+	storeDebug(page.Debug, &page.Mat, "fingers-masked")
+
+	text := textBounds(page.Mat)
+	for j := 0; j < contours.Size(); j++ {
+		r := gocv.BoundingRect(contours.At(j))
+		page.Fingers = append(page.Fingers, Finger{Rect: page.ToOriginal.Rect(r), CoversText: r.Overlaps(text)})
+	}
+	page.Debug.Notef("fingers", "text %v, fingers %+v", text, page.Fingers)
+
+	return nil
+}
+
+// findFingers returns the contours of the skin colored blobs that touch the
+// border of the image and are bigger than minArea (a fraction of the image).
+func findFingers(rec *debug.Recorder, i gocv.Mat, minArea float64) gocv.PointsVector {
+	skin := gocv.NewMat()
+	defer skin.Close()
+	// Skin tones of all ethnicities fall in a narrow range of the chroma
+	// channels, independent of the brightness
+	gocv.CvtColor(i, &skin, gocv.ColorBGRToYCrCb)
+	gocv.InRangeWithScalar(skin, gocv.NewScalar(0, 133, 77, 0), gocv.NewScalar(255, 173, 127, 0), &skin)
+
+	// Remove the speckles (e.g. brownish paper) and fill the holes (e.g. nails)
+	size := max(i.Cols()/100, 3)
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, goimage.Point{X: size, Y: size})
+	defer kernel.Close()
+	gocv.MorphologyEx(skin, &skin, gocv.MorphOpen, kernel)
+	gocv.MorphologyEx(skin, &skin, gocv.MorphClose, kernel)
+	storeDebug(rec, &skin, "fingers-skin")
+
+	all := gocv.FindContours(skin, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer all.Close()
+
+	imageArea := float64(i.Rows() * i.Cols())
+	// Fingers come from outside the photo so they touch its border
+	inner := goimage.Rect(0, 0, i.Cols(), i.Rows()).Inset(2)
+	fingers := [][]goimage.Point{}
+	for j := 0; j < all.Size(); j++ {
+		c := all.At(j)
+		if gocv.ContourArea(c)/imageArea < minArea {
+			continue
+		}
+		r := gocv.BoundingRect(c)
+		if r.In(inner) {
+			continue
+		}
+		fingers = append(fingers, c.ToPoints())
+	}
+
+	return gocv.NewPointsVectorFromPoints(fingers)
+}
+
+// textBounds returns the rectangle that contains the rows and the columns
+// with a noticeable amount of text. It is empty if there is no text.
+func textBounds(i gocv.Mat) goimage.Rectangle {
+	gray := i.Clone()
+	defer gray.Close()
+	convertToGrayscale(&gray)
+	text := gocv.NewMat()
+	defer text.Close()
+	gocv.AdaptiveThreshold(gray, &text, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(gray), 15)
+
+	top, bottom := textRange(text, 1)
+	left, right := textRange(text, 0)
+	if top < 0 || left < 0 {
+		return goimage.Rectangle{}
+	}
+
+	return goimage.Rect(left, top, right+1, bottom+1)
+}
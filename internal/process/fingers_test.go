@@ -0,0 +1,73 @@
+package process_test
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+// skin is a skin tone, inside the chroma range of the fingers stage
+var skin = color.RGBA{R: 224, G: 172, B: 105, A: 255}
+
+// colorPage returns textPage in color with a skin colored blob over rect
+func colorPage(rect image.Rectangle) gocv.Mat {
+	gray := textPage(230, 100)
+	defer gray.Close()
+	m := gocv.NewMat()
+	gocv.CvtColor(gray, &m, gocv.ColorGrayToBGR)
+	gocv.Rectangle(&m, rect, skin, -1)
+
+	return m
+}
+
+var _ = Describe("FingersStage", func() {
+	var stage FingersStage
+
+	BeforeEach(func() {
+		p, err := NewProcessorFromSpec("fingers")
+		Expect(err).ToNot(HaveOccurred())
+		stage = p.Stages[0].(FingersStage)
+	})
+
+	It("finds a finger entering from the edge of the photo", func() {
+		finger := image.Rect(400, 1100, 520, 1400)
+		m := colorPage(finger)
+		defer m.Close()
+		page := &Page{Mat: m, ToOriginal: geom.Identity()}
+
+		Expect(stage.Apply(page)).To(Succeed())
+		Expect(page.Fingers).To(HaveLen(1))
+		Expect(page.Fingers[0].CoversText).To(BeTrue())
+		Expect(page.Fingers[0].Rect.Overlaps(finger)).To(BeTrue())
+		// It is painted white
+		Expect(page.Mat.GetVecbAt(1300, 460)).To(Equal(gocv.Vecb{255, 255, 255}))
+	})
+
+	It("ignores skin colored blobs inside the photo", func() {
+		m := colorPage(image.Rect(400, 600, 520, 800))
+		defer m.Close()
+		page := &Page{Mat: m, ToOriginal: geom.Identity()}
+
+		Expect(stage.Apply(page)).To(Succeed())
+		Expect(page.Fingers).To(BeEmpty())
+	})
+
+	It("ignores blobs cut by the selection", func() {
+		m := colorPage(image.Rect(400, 600, 520, 800))
+		defer m.Close()
+		photo, err := img.FromMat(m)
+		Expect(err).ToNot(HaveOccurred())
+		selection, err := ParseSelection("300,500,400,200")
+		Expect(err).ToNot(HaveOccurred())
+
+		result, err := PipelineProcessor{Stages: []Stage{stage}}.Process(photo, Options{Selection: selection})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Pages[0].Fingers).To(BeEmpty())
+	})
+})
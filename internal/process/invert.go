@@ -0,0 +1,21 @@
+package process
+
+import (
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("invert", func(Params) (Stage, error) { return InvertStage{}, nil })
+}
+
+// InvertStage makes light text on a dark background (e.g. book covers,
+// signs) dark text on a light background, which is what the rest of the
+// stages and tesseract expect
+type InvertStage struct{}
+
+func (s InvertStage) Name() string { return "invert" }
+
+func (s InvertStage) Apply(page *Page) error {
+	gocv.BitwiseNot(page.Mat, &page.Mat)
+	return nil
+}
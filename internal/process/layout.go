@@ -0,0 +1,131 @@
+package process
+
+import (
+	goimage "image"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("layout", newLayoutStage)
+	RegisterStage("margins", newMarginsStage)
+}
+
+// LayoutStage segments the page into blocks of text and detects columns.
+// The regions are stored in the page in reading order so that each one of
+// them can be OCR'd on its own. Without it, tesseract reads multi column
+// pages (magazines, newspapers, papers) as a single block and interleaves
+// the columns. The lines at the top and the bottom of the page are marked as
+// headers, footers or page numbers so that they can be skipped.
+type LayoutStage struct {
+	RightToLeft bool
+}
+
+func newLayoutStage(params Params) (Stage, error) {
+	direction := params.String("direction", "ltr")
+	if direction != "ltr" && direction != "rtl" {
+		return nil, errors.Errorf("direction should be ltr or rtl, got %q", direction)
+	}
+
+	return LayoutStage{RightToLeft: direction == "rtl"}, nil
+}
+
+func (s LayoutStage) Name() string { return "layout" }
+
+func (s LayoutStage) Apply(page *Page) error {
+	regions, err := segmentPage(page.Debug, page.Mat, s.RightToLeft)
+	if err != nil {
+		return err
+	}
+	page.Regions = regions
+	page.Debug.Notef("layout", "regions: %+v", page.Regions)
+
+	// Debug
+	regionsCopy := page.Mat.Clone()
+	defer regionsCopy.Close()
+	drawRegions(&regionsCopy, page.Regions)
+	storeDebug(page.Debug, &regionsCopy, "layout-regions")
+
+	return nil
+}
+
+// MarginsStage finds the headers, the footers and the page numbers of a page
+// with a single column of text, so that they are not read together with the
+// text. The rest of the page is kept as one block, like without regions. It
+// does nothing if the regions are already set (e.g. by the layout stage).
+type MarginsStage struct{}
+
+func newMarginsStage(params Params) (Stage, error) {
+	return MarginsStage{}, nil
+}
+
+func (s MarginsStage) Name() string { return "margins" }
+
+func (s MarginsStage) Apply(page *Page) error {
+	if len(page.Regions) > 0 {
+		return nil
+	}
+
+	regions, err := segmentPage(page.Debug, page.Mat, false)
+	if err != nil {
+		return err
+	}
+	page.Regions = layout.Margins(regions, goimage.Rect(0, 0, page.Mat.Cols(), page.Mat.Rows()))
+	page.Debug.Notef("margins", "regions: %+v", page.Regions)
+
+	return nil
+}
+
+// segmentPage returns the blocks of text on the page in reading order, with
+// the headers, the footers and the page numbers marked. It returns no regions
+// if there is not enough text on the page to tell.
+func segmentPage(rec *debug.Recorder, i gocv.Mat, rightToLeft bool) ([]layout.Region, error) {
+	m, ok := EstimateTextMetrics(i)
+	if !ok {
+		return nil, nil
+	}
+	rec.Notef("layout", "text metrics: %+v", m)
+
+	mask := i.Clone()
+	defer mask.Close()
+	if mask.Channels() > 1 {
+		convertToGrayscale(&mask)
+	}
+	gocv.AdaptiveThreshold(mask, &mask, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(mask), 15)
+
+	// Join the characters into words, the words into lines and the lines
+	// into blocks, but keep the gaps between the columns
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, goimage.Point{
+		X: max(m.GlyphWidth*3/2, 1),
+		Y: max(m.LineSpacing-m.GlyphHeight+2, 1),
+	})
+	defer kernel.Close()
+	gocv.Dilate(mask, &mask, kernel)
+	storeDebug(rec, &mask, "layout-mask")
+
+	maskImg, err := mask.ToImage()
+	if err != nil {
+		return nil, errors.Wrap(err, "converting the mask to an image")
+	}
+	gray, ok := maskImg.(*goimage.Gray)
+	if !ok {
+		return nil, errors.New("the layout mask is not a grayscale image")
+	}
+
+	regions := layout.Segment(gray, layout.Options{
+		MinColumnGap: m.GlyphHeight,
+		MinBlockGap:  max(m.GlyphHeight/2, 1),
+		MinWidth:     m.GlyphWidth,
+		MinHeight:    m.GlyphHeight / 2,
+		RightToLeft:  rightToLeft,
+	})
+	// A dilated line is about one line spacing tall. Running heads are set
+	// apart from the text by more than the space between two lines and page
+	// numbers are a few digits wide.
+	layout.MarkMargins(regions, m.LineSpacing*3/2, m.LineSpacing, 5*m.GlyphWidth)
+
+	return regions, nil
+}
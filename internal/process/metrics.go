@@ -0,0 +1,103 @@
+package process
+
+import (
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+// TextMetrics describes the size of the text in an image, in pixels
+type TextMetrics struct {
+	// GlyphHeight and GlyphWidth are the median size of the characters
+	GlyphHeight int
+	GlyphWidth  int
+	// LineSpacing is the distance between two consecutive lines of text
+	LineSpacing int
+}
+
+// EstimateTextMetrics estimates the size of the characters and the distance
+// between lines from the connected components of the image. The components
+// that are too big or too small to be characters (pictures, page edges,
+// noise) are ignored. Returns false if there are not enough characters to
+// tell.
+func EstimateTextMetrics(i gocv.Mat) (TextMetrics, bool) {
+	text := i.Clone()
+	defer text.Close()
+	if text.Channels() > 1 {
+		convertToGrayscale(&text)
+	}
+	gocv.AdaptiveThreshold(text, &text, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(text), 15)
+
+	labels, stats, centroids := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer labels.Close()
+	defer stats.Close()
+	defer centroids.Close()
+	n := gocv.ConnectedComponentsWithStats(text, &labels, &stats, &centroids)
+
+	heights, widths := []int{}, []int{}
+	for label := 1; label < n; label++ { // label 0 is the background
+		w := int(stats.GetIntAt(label, int(gocv.CC_STAT_WIDTH)))
+		h := int(stats.GetIntAt(label, int(gocv.CC_STAT_HEIGHT)))
+		area := int(stats.GetIntAt(label, int(gocv.CC_STAT_AREA)))
+		if h < 4 || w < 2 || area < 8 {
+			continue // noise
+		}
+		if h > i.Rows()/10 || w > i.Cols()/10 {
+			continue // pictures, lines, page edges
+		}
+		if w > 3*h || h > 5*w {
+			continue // not shaped like a character
+		}
+		heights = append(heights, h)
+		widths = append(widths, w)
+	}
+
+	// Too few characters to say anything about them
+	if len(heights) < 20 {
+		return TextMetrics{}, false
+	}
+
+	m := TextMetrics{GlyphHeight: median(heights), GlyphWidth: median(widths)}
+	m.LineSpacing = lineSpacing(text, m.GlyphHeight)
+
+	return m, true
+}
+
+// lineSpacing finds the period of the text lines in the amount of text on
+// each row, using autocorrelation. Lines are expected to be between 1.2 and 4
+// glyph heights apart.
+func lineSpacing(text gocv.Mat, glyphHeight int) int {
+	sums := gocv.NewMat()
+	defer sums.Close()
+	gocv.Reduce(text, &sums, 1, gocv.ReduceAvg, gocv.MatTypeCV32F)
+
+	profile := make([]float64, sums.Rows())
+	for y := range profile {
+		profile[y] = float64(sums.GetFloatAt(y, 0))
+	}
+	m := mean(profile)
+	for y := range profile {
+		profile[y] -= m
+	}
+
+	best, bestScore := 2*glyphHeight, 0.0
+	for lag := glyphHeight * 12 / 10; lag <= 4*glyphHeight && lag < len(profile); lag++ {
+		score := 0.0
+		for y := 0; y+lag < len(profile); y++ {
+			score += profile[y] * profile[y+lag]
+		}
+		score /= float64(len(profile) - lag)
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+
+	return best
+}
+
+func median(values []int) int {
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+
+	return sorted[len(sorted)/2]
+}
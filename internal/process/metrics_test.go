@@ -0,0 +1,49 @@
+package process_test
+
+import (
+	"image"
+	"image/color"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+// linesPage returns a grayscale page with lines of text the given distance
+// apart
+func linesPage(spacing int) gocv.Mat {
+	m := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(230, 0, 0, 0), 1400, 1000, gocv.MatTypeCV8U)
+	for y := 150; y < 1300; y += spacing {
+		gocv.PutText(&m, "the quick brown fox jumps", image.Point{X: 100, Y: y}, gocv.FontHersheySimplex, 1.5, color.RGBA{A: 255}, 3)
+	}
+
+	return m
+}
+
+var _ = Describe("EstimateTextMetrics", func() {
+	DescribeTable("measures the lines",
+		func(spacing int) {
+			m := linesPage(spacing)
+			defer m.Close()
+
+			metrics, ok := EstimateTextMetrics(m)
+			Expect(ok).To(BeTrue())
+			// The letters of the font are about 20 to 35 pixels high and
+			// half as wide
+			Expect(metrics.GlyphHeight).To(BeNumerically("~", 28, 10))
+			Expect(metrics.GlyphWidth).To(BeNumerically("~", 20, 10))
+			Expect(metrics.LineSpacing).To(BeNumerically("~", spacing, 2))
+		},
+		Entry("close lines", 50),
+		Entry("far lines", 90),
+	)
+
+	It("can't tell without text", func() {
+		m := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(230, 0, 0, 0), 1400, 1000, gocv.MatTypeCV8U)
+		defer m.Close()
+
+		_, ok := EstimateTextMetrics(m)
+		Expect(ok).To(BeFalse())
+	})
+})
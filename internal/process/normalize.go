@@ -0,0 +1,111 @@
+package process
+
+import (
+	goimage "image"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("normalize", newNormalizeStage)
+}
+
+// NormalizeStage evens out the illumination of the page. It estimates the
+// background (the paper without the text) and divides it out of the image so
+// that lamp shadows or a dark gutter don't end up as black areas after the
+// thresholding. Optionally it also applies CLAHE to boost the local contrast.
+type NormalizeStage struct {
+	// Background is the method used to estimate the background, "blur" or
+	// "close" (morphological closing)
+	Background string
+	// Kernel is the size of the kernel used to estimate the background.
+	// When 0, it is calculated from the image size.
+	Kernel int
+	// CLAHE enables the Contrast Limited Adaptive Histogram Equalization
+	CLAHE     bool
+	ClipLimit float64
+	Tiles     int
+}
+
+func newNormalizeStage(params Params) (Stage, error) {
+	var err error
+	s := NormalizeStage{Background: params.String("background", "close")}
+	if s.Background != "blur" && s.Background != "close" {
+		return nil, errors.Errorf("background should be blur or close, got %q", s.Background)
+	}
+	if s.Kernel, err = params.Int("kernel", 0); err != nil {
+		return nil, err
+	}
+	if s.CLAHE, err = params.Bool("clahe", false); err != nil {
+		return nil, err
+	}
+	if s.ClipLimit, err = params.Float("clip", 2.0); err != nil {
+		return nil, err
+	}
+	if s.Tiles, err = params.Int("tiles", 8); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s NormalizeStage) Name() string { return "normalize" }
+
+func (s NormalizeStage) Apply(page *Page) error {
+	if page.Mat.Channels() > 1 {
+		convertToGrayscale(&page.Mat)
+	}
+
+	kernelSize := s.Kernel
+	if kernelSize == 0 {
+		kernelSize = odd(page.Mat.Cols() / 20)
+	}
+
+	background := gocv.NewMat()
+	defer background.Close()
+	switch s.Background {
+	case "blur":
+		gocv.Blur(page.Mat, &background, goimage.Point{X: kernelSize, Y: kernelSize})
+	case "close":
+		// Closing removes the (dark) text and leaves the paper
+		kernel := gocv.GetStructuringElement(gocv.MorphEllipse, goimage.Point{X: kernelSize, Y: kernelSize})
+		defer kernel.Close()
+		gocv.MorphologyEx(page.Mat, &background, gocv.MorphClose, kernel)
+	}
+	storeDebug(page.Debug, &background, "normalize-background")
+
+	imgF := gocv.NewMat()
+	defer imgF.Close()
+	page.Mat.ConvertTo(&imgF, gocv.MatTypeCV32F)
+	backgroundF := gocv.NewMat()
+	defer backgroundF.Close()
+	background.ConvertTo(&backgroundF, gocv.MatTypeCV32F)
+	backgroundF.AddFloat(1) // Avoid divisions by zero
+
+	gocv.Divide(imgF, backgroundF, &imgF)
+	imgF.MultiplyFloat(255)
+	imgF.ConvertTo(&page.Mat, gocv.MatTypeCV8U)
+	storeDebug(page.Debug, &page.Mat, "normalize-divided")
+
+	if s.CLAHE {
+		clahe := gocv.NewCLAHEWithParams(s.ClipLimit, goimage.Point{X: s.Tiles, Y: s.Tiles})
+		defer clahe.Close()
+		clahe.Apply(page.Mat, &page.Mat)
+		storeDebug(page.Debug, &page.Mat, "normalize-clahe")
+	}
+
+	return nil
+}
+
+// odd returns the smallest odd number that is >= n and at least 3. Many
+// OpenCV functions only accept odd kernel sizes.
+func odd(n int) int {
+	if n < 3 {
+		return 3
+	}
+	if n%2 == 0 {
+		return n + 1
+	}
+	return n
+}
@@ -0,0 +1,161 @@
+package process
+
+import (
+	goimage "image"
+	"math"
+	"strings"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("orient", newOrientStage)
+}
+
+// OrientStage detects photos taken sideways or upside down and rotates them
+// so that the text is upright. It first decides whether the text lines are
+// horizontal or vertical and then runs tesseract on the two remaining
+// candidate orientations, keeping the one with the highest confidence.
+// The rotation applied is stored in the Orientation of the page.
+type OrientStage struct {
+	// Languages are the tesseract languages used to score the orientations.
+	// When empty, the languages of the page are used.
+	Languages []string
+}
+
+func newOrientStage(params Params) (Stage, error) {
+	s := OrientStage{}
+	if lang := params.String("lang", ""); lang != "" {
+		s.Languages = strings.Split(lang, "+")
+	}
+
+	return s, nil
+}
+
+func (s OrientStage) Name() string { return "orient" }
+
+func (s OrientStage) Apply(page *Page) error {
+	candidates := []int{0, 180}
+	if !hasHorizontalLines(page.Mat) {
+		candidates = []int{90, 270}
+	}
+
+	languages := s.Languages
+	if len(languages) == 0 {
+		languages = page.Languages
+	}
+	if len(languages) == 0 {
+		languages = []string{"eng"}
+	}
+
+	// The page is left as it is when no orientation finds any words
+	best, bestScore := 0, 0.0
+	for _, degrees := range candidates {
+		score, err := s.score(page.Mat, degrees, ocr.TesseractOCR{Languages: languages, Pool: page.Pool})
+		if err != nil {
+			return errors.Wrapf(err, "scoring orientation %d", degrees)
+		}
+		page.Debug.Notef("orient", "%d degrees: confidence %.1f", degrees, score)
+		if score > bestScore {
+			best, bestScore = degrees, score
+		}
+	}
+
+	page.moved(geom.RotationClockwise(best, goimage.Point{X: page.Mat.Cols(), Y: page.Mat.Rows()}))
+	rotateClockwise(&page.Mat, best)
+	page.Orientation = best
+
+	return nil
+}
+
+// score rotates a copy of the center of the image and returns the mean
+// confidence of the words tesseract finds in it, weighted by word length
+func (s OrientStage) score(i gocv.Mat, degrees int, o ocr.OCR) (float64, error) {
+	// The center of the page is enough to tell and much faster to OCR
+	center := i.Region(goimage.Rect(i.Cols()/4, i.Rows()/4, i.Cols()*3/4, i.Rows()*3/4))
+	rotated := center.Clone()
+	center.Close()
+	defer rotated.Close()
+	rotateClockwise(&rotated, degrees)
+
+	rotatedImg, err := img.FromMat(rotated)
+	if err != nil {
+		return 0, err
+	}
+	result, err := o.Recognize(rotatedImg)
+	if err != nil {
+		return 0, errors.Wrap(err, "detecting words")
+	}
+
+	total, length := 0.0, 0
+	for _, w := range result.Words() {
+		l := len(strings.TrimSpace(w.Text))
+		total += w.Confidence * float64(l)
+		length += l
+	}
+	if length == 0 {
+		return 0, nil
+	}
+
+	return total / float64(length), nil
+}
+
+// hasHorizontalLines returns true if the text in the image runs in horizontal
+// lines. Horizontal lines make the amount of text on each row change a lot
+// between lines and the gaps between them, while the amount of text in each
+// column stays about the same. For vertical lines it's the other way around.
+func hasHorizontalLines(i gocv.Mat) bool {
+	text := i.Clone()
+	defer text.Close()
+	if text.Channels() > 1 {
+		convertToGrayscale(&text)
+	}
+	gocv.AdaptiveThreshold(text, &text, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(text), 15)
+
+	return profileVariation(text, 1) >= profileVariation(text, 0)
+}
+
+// profileVariation returns the coefficient of variation of the sums of the
+// rows (dim 1) or the columns (dim 0) of the image
+func profileVariation(i gocv.Mat, dim int) float64 {
+	sums := gocv.NewMat()
+	defer sums.Close()
+	gocv.Reduce(i, &sums, dim, gocv.ReduceSum, gocv.MatTypeCV32F)
+
+	values := []float64{}
+	for j := 0; j < sums.Total(); j++ {
+		if dim == 0 {
+			values = append(values, float64(sums.GetFloatAt(0, j)))
+		} else {
+			values = append(values, float64(sums.GetFloatAt(j, 0)))
+		}
+	}
+
+	m := mean(values)
+	if m == 0 {
+		return 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - m) * (v - m)
+	}
+	variance /= float64(len(values))
+
+	return math.Sqrt(variance) / m
+}
+
+// rotateClockwise rotates the image by 0, 90, 180 or 270 degrees
+func rotateClockwise(i *gocv.Mat, degrees int) {
+	switch degrees {
+	case 90:
+		gocv.Rotate(*i, i, gocv.Rotate90Clockwise)
+	case 180:
+		gocv.Rotate(*i, i, gocv.Rotate180Clockwise)
+	case 270:
+		gocv.Rotate(*i, i, gocv.Rotate90CounterClockwise)
+	}
+}
@@ -0,0 +1,51 @@
+package process_test
+
+import (
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+var _ = Describe("OrientStage", func() {
+	var m gocv.Mat
+	var page *Page
+
+	BeforeEach(func() {
+		m = textPage(230, 100)
+		page = &Page{Mat: m, ToOriginal: geom.Identity()}
+	})
+
+	AfterEach(func() {
+		page.Mat.Close()
+	})
+
+	It("leaves upright pages as they are", func() {
+		Expect(OrientStage{}.Apply(page)).To(Succeed())
+		Expect(page.Orientation).To(Equal(0))
+	})
+
+	It("turns upside down pages", func() {
+		gocv.Rotate(m, &page.Mat, gocv.Rotate180Clockwise)
+
+		Expect(OrientStage{}.Apply(page)).To(Succeed())
+		Expect(page.Orientation).To(Equal(180))
+	})
+
+	It("turns sideways pages", func() {
+		gocv.Rotate(m, &page.Mat, gocv.Rotate90Clockwise)
+
+		Expect(OrientStage{}.Apply(page)).To(Succeed())
+		Expect(page.Orientation).To(Equal(270))
+		Expect(page.Mat.Rows()).To(Equal(1400))
+	})
+
+	It("leaves blank pages as they are", func() {
+		page.Mat.SetTo(gocv.NewScalar(230, 0, 0, 0))
+
+		Expect(OrientStage{}.Apply(page)).To(Succeed())
+		Expect(page.Orientation).To(Equal(0))
+		Expect(page.Mat.Rows()).To(Equal(1400))
+	})
+})
@@ -0,0 +1,189 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("perspective", newPerspectiveStage)
+}
+
+// PerspectiveStage looks for the four corners of the page and warps the
+// image so that the page becomes a flat rectangle. This fixes the keystone
+// distortion of photos taken with a handheld phone which a rotation alone
+// can't fix. When no quadrilateral is found, it falls back to the deskew step.
+type PerspectiveStage struct {
+	// MinArea is the minimum area of the page as a fraction of the image
+	// area. Smaller quadrilaterals are ignored.
+	MinArea float64
+}
+
+func newPerspectiveStage(params Params) (Stage, error) {
+	minArea, err := params.Float("min-area", 0.25)
+	if err != nil {
+		return nil, err
+	}
+	if minArea <= 0 || minArea > 1 {
+		return nil, errors.New("min-area should be between 0 and 1")
+	}
+
+	return PerspectiveStage{MinArea: minArea}, nil
+}
+
+func (s PerspectiveStage) Name() string { return "perspective" }
+
+func (s PerspectiveStage) Apply(page *Page) error {
+	corners, ok := findQuadrilateral(page.Debug, page.Mat, s.MinArea)
+	if !ok {
+		page.Debug.Notef("perspective", "no quadrilateral found, falling back to deskew")
+		return DeskewStage{Crop: true}.Apply(page)
+	}
+	page.Debug.Notef("perspective", "corners: %v", corners)
+
+	// Debug
+	quadCopy := page.Mat.Clone()
+	defer quadCopy.Close()
+	quadV := gocv.NewPointsVectorFromPoints([][]goimage.Point{corners})
+	defer quadV.Close()
+	gocv.DrawContours(&quadCopy, quadV, -1, color.RGBA{0, 255, 0, 255}, 3)
+	storeDebug(page.Debug, &quadCopy, "perspective-quadrilateral")
+
+	page.moved(warpPerspective(page.Debug, &page.Mat, corners))
+
+	return nil
+}
+
+// findQuadrilateral returns the corners of the biggest four sided contour in
+// the image, ordered as top-left, top-right, bottom-right, bottom-left.
+// The second return value is false if no such contour was found.
+func findQuadrilateral(rec *debug.Recorder, i gocv.Mat, minArea float64) ([]goimage.Point, bool) {
+	gray := i.Clone()
+	defer gray.Close()
+	if gray.Channels() > 1 {
+		convertToGrayscale(&gray)
+	}
+
+	gocv.GaussianBlur(gray, &gray, goimage.Point{X: 5, Y: 5}, 0, 0, gocv.BorderDefault)
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.Canny(gray, &edges, 50, 150)
+
+	// Close small gaps in the page outline
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, goimage.Point{X: 3, Y: 3})
+	defer kernel.Close()
+	gocv.Dilate(edges, &edges, kernel)
+	storeDebug(rec, &edges, "perspective-edges")
+
+	points := gocv.FindContours(edges, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer points.Close()
+	contours := ContoursBySize{}
+	for j := 0; j < points.Size(); j++ {
+		contours = append(contours, Contour{OriginalIdx: j, Contour: points.At(j)})
+	}
+	sort.Sort(sort.Reverse(contours))
+
+	imgArea := float64(i.Rows() * i.Cols())
+	for _, c := range contours {
+		if gocv.ContourArea(c.Contour) < minArea*imgArea {
+			break
+		}
+
+		approx := gocv.ApproxPolyDP(c.Contour, 0.02*gocv.ArcLength(c.Contour, true), true)
+		corners := approx.ToPoints()
+		approx.Close()
+		if len(corners) == 4 && isConvex(corners) {
+			return orderCorners(corners), true
+		}
+	}
+
+	return nil, false
+}
+
+// warpPerspective maps the given corners (ordered as returned by
+// findQuadrilateral) to a straight rectangle. It returns how the pixels were
+// moved.
+func warpPerspective(rec *debug.Recorder, i *gocv.Mat, corners []goimage.Point) geom.Transform {
+	tl, tr, br, bl := corners[0], corners[1], corners[2], corners[3]
+	width := int(math.Max(distance(tl, tr), distance(bl, br)))
+	height := int(math.Max(distance(tl, bl), distance(tr, br)))
+
+	src := gocv.NewPointVectorFromPoints(corners)
+	defer src.Close()
+	dst := gocv.NewPointVectorFromPoints([]goimage.Point{
+		{0, 0},
+		{width - 1, 0},
+		{width - 1, height - 1},
+		{0, height - 1},
+	})
+	defer dst.Close()
+
+	m := gocv.GetPerspectiveTransform(src, dst)
+	defer m.Close()
+
+	warped := gocv.NewMat()
+	gocv.WarpPerspectiveWithParams(*i, &warped, m, goimage.Point{X: width, Y: height}, gocv.InterpolationCubic, gocv.BorderReplicate, color.RGBA{})
+	storeDebug(rec, &warped, "perspective-warped")
+
+	i.Close()
+	*i = warped
+
+	return matTransform(m)
+}
+
+// orderCorners sorts four points as top-left, top-right, bottom-right,
+// bottom-left. The top-left corner has the smallest x+y sum and the
+// bottom-right the biggest. The top-right has the smallest y-x difference
+// and the bottom-left the biggest.
+func orderCorners(pts []goimage.Point) []goimage.Point {
+	result := make([]goimage.Point, 4)
+	minSum, maxSum := math.MaxInt, math.MinInt
+	minDiff, maxDiff := math.MaxInt, math.MinInt
+	for _, p := range pts {
+		if sum := p.X + p.Y; sum < minSum {
+			minSum, result[0] = sum, p
+		}
+		if sum := p.X + p.Y; sum > maxSum {
+			maxSum, result[2] = sum, p
+		}
+		if diff := p.Y - p.X; diff < minDiff {
+			minDiff, result[1] = diff, p
+		}
+		if diff := p.Y - p.X; diff > maxDiff {
+			maxDiff, result[3] = diff, p
+		}
+	}
+
+	return result
+}
+
+// isConvex returns true if the polygon described by pts is convex. That is
+// when the cross products of all consecutive edges have the same sign.
+func isConvex(pts []goimage.Point) bool {
+	sign := 0
+	for j := range pts {
+		a, b, c := pts[j], pts[(j+1)%len(pts)], pts[(j+2)%len(pts)]
+		cross := (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+		switch {
+		case cross > 0 && sign < 0, cross < 0 && sign > 0:
+			return false
+		case cross > 0:
+			sign = 1
+		case cross < 0:
+			sign = -1
+		}
+	}
+
+	return sign != 0
+}
+
+func distance(a, b goimage.Point) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
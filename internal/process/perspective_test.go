@@ -0,0 +1,27 @@
+package process_test
+
+import (
+	"image"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrderCorners", func() {
+	It("orders the corners of a rotated page", func() {
+		// A page rotated a bit clockwise, corners in random order
+		corners := []image.Point{{X: 480, Y: 650}, {X: 120, Y: 40}, {X: 60, Y: 600}, {X: 540, Y: 90}}
+		Expect(OrderCorners(corners)).To(Equal([]image.Point{
+			{X: 120, Y: 40},  // top-left
+			{X: 540, Y: 90},  // top-right
+			{X: 480, Y: 650}, // bottom-right
+			{X: 60, Y: 600},  // bottom-left
+		}))
+	})
+
+	It("orders the corners of a straight page", func() {
+		corners := []image.Point{{X: 0, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 0}, {X: 0, Y: 0}}
+		Expect(OrderCorners(corners)).To(Equal([]image.Point{{X: 0, Y: 0}, {X: 200, Y: 0}, {X: 200, Y: 100}, {X: 0, Y: 100}}))
+	})
+})
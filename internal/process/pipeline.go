@@ -0,0 +1,202 @@
+package process
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	"github.com/pkg/errors"
+)
+
+// DefaultStages is the list of stages used when none is configured with the
+// OOR_STAGES or the OOR_PROFILE environment variables.
+const DefaultStages = "quality,grayscale,orient,split,deskew,margins,upscale,threshold,border"
+
+// MinImageSize is the minimum width and height of an image, in pixels.
+// Smaller images can't have readable text.
+const MinImageSize = 100
+
+// Profiles are named lists of stages (and their parameters) tuned for
+// different kinds of documents. They can be selected with the OOR_PROFILE
+// environment variable.
+var Profiles = map[string]string{
+	"default": DefaultStages,
+	// Photos with lamp shadows, a dark gutter or otherwise uneven light
+	"shadows": "quality(min-brightness=30),grayscale,normalize(clahe=true),orient,split,deskew,margins,upscale,threshold(method=sauvola),border",
+	// Magazines, newspapers and papers with more than one column of text
+	"columns": "quality,grayscale,orient,deskew(crop=false),layout,figures,upscale,threshold,border",
+	// Thick books photographed with a handheld phone
+	"handheld": "fingers,quality,grayscale,normalize,orient,split,perspective,dewarp,margins,upscale,threshold(method=adaptive),border",
+}
+
+// PipelineProcessor is a Processor that applies a list of stages, in order,
+// on the image.
+type PipelineProcessor struct {
+	Stages []Stage
+}
+
+// NewPipelineProcessor returns a PipelineProcessor that applies the given stages
+func NewPipelineProcessor(stages ...Stage) PipelineProcessor {
+	return PipelineProcessor{Stages: stages}
+}
+
+// NewProcessorFromSpec returns a PipelineProcessor with the stages described
+// by spec. See ParseStages for the format.
+func NewProcessorFromSpec(spec string) (PipelineProcessor, error) {
+	specs, err := ParseStages(spec)
+	if err != nil {
+		return PipelineProcessor{}, errors.Wrap(err, "parsing the stages")
+	}
+
+	return NewProcessorFromStages(specs)
+}
+
+// NewProcessorFromStages returns a PipelineProcessor with the given stages
+func NewProcessorFromStages(specs []StageSpec) (PipelineProcessor, error) {
+	stages := []Stage{}
+	for _, s := range specs {
+		stage, err := NewStage(s.Name, s.Params)
+		if err != nil {
+			return PipelineProcessor{}, err
+		}
+		stages = append(stages, stage)
+	}
+
+	return NewPipelineProcessor(stages...), nil
+}
+
+// NewProcessorFromEnv returns a PipelineProcessor with the stages of
+// StagesFromEnv
+func NewProcessorFromEnv() (PipelineProcessor, error) {
+	spec, err := StagesFromEnv()
+	if err != nil {
+		return PipelineProcessor{}, err
+	}
+
+	return NewProcessorFromSpec(spec)
+}
+
+// StagesFromEnv returns the stages listed in the OOR_STAGES environment
+// variable. If that is not set, the stages of the profile in OOR_PROFILE are
+// returned and if that is not set either, the DefaultStages.
+func StagesFromEnv() (string, error) {
+	if spec := os.Getenv("OOR_STAGES"); spec != "" {
+		return spec, nil
+	}
+
+	profile := os.Getenv("OOR_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	spec, ok := Profiles[profile]
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q", profile)
+	}
+
+	return spec, nil
+}
+
+// Process prepares a photo of a book page for OCR by running all the stages
+// of the pipeline on the selected part of it. The PhotoStages judge the whole
+// photo instead. The image after each stage is recorded in the debug report.
+func (p PipelineProcessor) Process(image *img.Image, opts Options) (*Result, error) {
+	rec := opts.Debug
+	mat, err := image.ToMat()
+	if err != nil {
+		return nil, errors.Wrap(err, "converting the image to a Mat")
+	}
+
+	pages := []*Page{{Mat: mat, Languages: opts.Languages, Pool: opts.Pool, Debug: rec, ToOriginal: geom.Identity()}}
+	defer func() {
+		for _, page := range pages {
+			page.Mat.Close()
+		}
+	}()
+	if pages[0].Mat.Empty() {
+		return nil, ErrEmptyImage
+	}
+	storeDebug(rec, &pages[0].Mat, "0-original")
+	// A small selection of a large enough photo is fine
+	if pages[0].Mat.Rows() < MinImageSize || pages[0].Mat.Cols() < MinImageSize {
+		return nil, ErrImageTooSmall
+	}
+	if len(opts.Selection) > 0 {
+		for i, stage := range p.Stages {
+			if _, ok := stage.(PhotoStage); !ok {
+				continue
+			}
+			stopClock := rec.Time(fmt.Sprintf("stage-%d-%s", i+1, stage.Name()))
+			err := stage.Apply(pages[0])
+			stopClock()
+			if err != nil {
+				return nil, errors.Wrapf(err, "applying stage %q", stage.Name())
+			}
+		}
+	}
+
+	selected, err := applySelection(&pages[0].Mat, opts.Selection)
+	if err != nil {
+		return nil, err
+	}
+	pages[0].moved(geom.Translation(-float64(selected.Min.X), -float64(selected.Min.Y)))
+	if len(opts.Selection) > 0 {
+		storeDebug(rec, &pages[0].Mat, "0-selection")
+	}
+
+	for i, stage := range p.Stages {
+		if _, ok := stage.(PhotoStage); ok && len(opts.Selection) > 0 {
+			continue // already applied on the whole photo
+		}
+		stopClock := rec.Time(fmt.Sprintf("stage-%d-%s", i+1, stage.Name()))
+		next := []*Page{}
+		for _, page := range pages {
+			if splitter, ok := stage.(Splitter); ok {
+				parts, err := splitter.Split(page)
+				if err != nil {
+					return nil, errors.Wrapf(err, "applying stage %q", stage.Name())
+				}
+				next = append(next, parts...)
+				continue
+			}
+
+			if err := stage.Apply(page); err != nil {
+				return nil, errors.Wrapf(err, "applying stage %q", stage.Name())
+			}
+			next = append(next, page)
+		}
+		pages = next
+		stopClock()
+
+		for j, page := range pages {
+			storeDebug(rec, &page.Mat, debugName(fmt.Sprintf("stage-%d-%s", i+1, stage.Name()), j, len(pages)))
+		}
+	}
+
+	result := &Result{}
+	for _, page := range pages {
+		pageImg, err := img.FromMat(page.Mat)
+		if err != nil {
+			return nil, err
+		}
+		result.Pages = append(result.Pages, ResultPage{
+			Image:       pageImg,
+			Orientation: page.Orientation,
+			Quality:     page.Quality,
+			Regions:     page.Regions,
+			Fingers:     page.Fingers,
+			ToOriginal:  page.ToOriginal,
+		})
+	}
+
+	return result, nil
+}
+
+// debugName adds the page number to the name of a debug image when there is
+// more than one page
+func debugName(name string, page, pages int) string {
+	if pages < 2 {
+		return name
+	}
+	return fmt.Sprintf("%s-page-%d", name, page+1)
+}
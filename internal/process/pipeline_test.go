@@ -0,0 +1,47 @@
+package process_test
+
+import (
+	"github.com/jimmykarily/open-ocr-reader/internal/img"
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+var _ = Describe("PipelineProcessor", func() {
+	var processor PipelineProcessor
+
+	BeforeEach(func() {
+		var err error
+		processor, err = NewProcessorFromSpec(DefaultStages)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("reads a selection smaller than the minimum image size", func() {
+		m := textPage(230, 100)
+		defer m.Close()
+		photo, err := img.FromMat(m)
+		Expect(err).ToNot(HaveOccurred())
+		// A single line of text
+		selection, err := ParseSelection("90,110,500,60")
+		Expect(err).ToNot(HaveOccurred())
+
+		result, err := processor.Process(photo, Options{Selection: selection})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Pages).ToNot(BeEmpty())
+		// The quality is that of the whole photo, the selection cuts the
+		// text off on purpose
+		Expect(result.Pages[0].Quality).ToNot(BeNil())
+		Expect(result.Pages[0].Quality.Problems).ToNot(ContainElement(ProblemCutOff))
+	})
+
+	It("returns ErrImageTooSmall for small photos", func() {
+		m := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(230, 0, 0, 0), 50, 80, gocv.MatTypeCV8U)
+		defer m.Close()
+		photo, err := img.FromMat(m)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = processor.Process(photo, Options{})
+		Expect(err).To(MatchError(ErrImageTooSmall))
+	})
+})
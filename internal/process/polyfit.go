@@ -0,0 +1,82 @@
+package process
+
+import "math"
+
+// polynomial holds the coefficients of a polynomial, lowest degree first
+type polynomial []float64
+
+// fitPolynomial returns the polynomial of the given degree that best fits
+// the points (least squares). It returns nil if there are not enough points
+// or the system can't be solved.
+func fitPolynomial(xs, ys []float64, degree int) polynomial {
+	n := degree + 1
+	if len(xs) < n || len(xs) != len(ys) {
+		return nil
+	}
+
+	// Normal equations: (A^T A) c = A^T y where A[i][j] = x_i^j
+	m := make([][]float64, n)
+	for j := range m {
+		m[j] = make([]float64, n+1)
+	}
+	for i, x := range xs {
+		powers := make([]float64, 2*n)
+		powers[0] = 1
+		for j := 1; j < len(powers); j++ {
+			powers[j] = powers[j-1] * x
+		}
+		for r := 0; r < n; r++ {
+			for c := 0; c < n; c++ {
+				m[r][c] += powers[r+c]
+			}
+			m[r][n] += powers[r] * ys[i]
+		}
+	}
+
+	return solve(m)
+}
+
+// solve solves the linear system described by the augmented matrix m using
+// Gaussian elimination with partial pivoting
+func solve(m [][]float64) []float64 {
+	n := len(m)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for c := col; c <= n; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+
+	result := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := m[r][n]
+		for c := r + 1; c < n; c++ {
+			sum -= m[r][c] * result[c]
+		}
+		result[r] = sum / m[r][r]
+	}
+
+	return result
+}
+
+// at evaluates the polynomial at x
+func (p polynomial) at(x float64) float64 {
+	result := 0.0
+	for j := len(p) - 1; j >= 0; j-- {
+		result = result*x + p[j]
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package process_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FitPolynomial", func() {
+	It("fits a quadratic exactly", func() {
+		// y = 2 - 3x + 0.5x^2
+		xs := []float64{-2, -1, 0, 1, 2, 3}
+		ys := []float64{}
+		for _, x := range xs {
+			ys = append(ys, 2-3*x+0.5*x*x)
+		}
+
+		p := FitPolynomial(xs, ys, 2)
+		Expect(p).To(HaveLen(3))
+		Expect(p[0]).To(BeNumerically("~", 2, 1e-9))
+		Expect(p[1]).To(BeNumerically("~", -3, 1e-9))
+		Expect(p[2]).To(BeNumerically("~", 0.5, 1e-9))
+	})
+
+	It("returns nil without enough points", func() {
+		Expect(FitPolynomial([]float64{1, 2}, []float64{1, 2}, 2)).To(BeNil())
+	})
+
+	It("returns nil when all the points have the same x", func() {
+		Expect(FitPolynomial([]float64{1, 1, 1}, []float64{1, 2, 3}, 1)).To(BeNil())
+	})
+})
+
+var _ = Describe("Solve", func() {
+	It("solves a linear system", func() {
+		// x + 2y = 5, 3x - y = 1
+		Expect(Solve([][]float64{{1, 2, 5}, {3, -1, 1}})).To(Equal([]float64{1, 2}))
+	})
+
+	It("returns nil for a singular system", func() {
+		Expect(Solve([][]float64{{1, 2, 3}, {2, 4, 6}})).To(BeNil())
+	})
+})
@@ -3,24 +3,75 @@
 package process
 
 import (
-	"fmt"
 	goimage "image"
 	"image/color"
 	"math"
-	"os"
-	"path/filepath"
 	"sort"
+	"strconv"
 
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
 	"github.com/jimmykarily/open-ocr-reader/internal/img"
-	"github.com/pkg/errors"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
 	"gocv.io/x/gocv"
 )
 
 type Processor interface {
-	Process(*img.Image) (*img.Image, error)
+	Process(image *img.Image, opts Options) (*Result, error)
 }
 
-type DefaultProcessor struct{}
+// Options are the settings of a single run of a Processor
+type Options struct {
+	// Selection is the part of the image to read. The whole image is read
+	// when empty.
+	Selection Selection
+	// Languages are the tesseract languages of the text, for the stages
+	// that read it (e.g. orient). English is assumed when empty.
+	Languages []string
+	// Pool provides the tesseract clients of the stages that read the text.
+	// New clients are created when nil.
+	Pool *ocr.Pool
+	// Debug records the intermediate results of the run. It can be nil.
+	Debug *debug.Recorder
+}
+
+// Result is the outcome of processing a photo. A photo can contain more
+// than one page (e.g. an open book) so there can be more than one page in
+// the result.
+type Result struct {
+	// Pages are the processed pages in reading order
+	Pages []ResultPage
+}
+
+// ResultPage is a single processed page, ready for OCR
+type ResultPage struct {
+	Image *img.Image
+	// Orientation is the clockwise rotation (0, 90, 180 or 270 degrees)
+	// applied to the page to make the text upright
+	Orientation int
+	// Quality is the quality of the photo the page was found in, if measured
+	Quality *Quality
+	// Regions are the parts of the page to OCR, in reading order. When
+	// empty, the whole page should be OCR'd.
+	Regions []layout.Region
+	// Fingers are the fingers found over the page, if looked for
+	Fingers []Finger
+	// ToOriginal maps the coordinates of the page image to the coordinates
+	// of the photo
+	ToOriginal geom.Transform
+}
+
+// FingersOverText returns true if any finger covers some of the text
+func (p ResultPage) FingersOverText() bool {
+	for _, f := range p.Fingers {
+		if f.CoversText {
+			return true
+		}
+	}
+
+	return false
+}
 
 type Contour struct {
 	OriginalIdx int
@@ -34,84 +85,70 @@ func (s ContoursBySize) Less(i, j int) bool {
 }
 func (s ContoursBySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-// NewDefaultProcessor returns a DefaultProcessor
-func NewDefaultProcessor() DefaultProcessor {
-	return DefaultProcessor{}
-}
-
-// Process prepares a photo of a book page for OCR
-// These are the steps
-// - Make the image black and white
-// - Find the biggest block of text
-// - Find a containing rectangle of that block of text and deskew the image
-//   based on that rectangle (align the text vertically)
-// - Crop image to that rectangle
+// NewDefaultProcessor returns a PipelineProcessor with the DefaultStages.
+// These are the steps:
+//   - Make the image black and white
+//   - Split two page spreads (e.g. an open book) in two pages
+//   - Find the biggest block of text
+//   - Find a containing rectangle of that block of text and deskew the image
+//     based on that rectangle (align the text vertically)
+//   - Crop image to that rectangle
+//   - Enlarge the image if the text is too small for OCR
+//
 // Heavily inspired by these:
 // https://github.com/JPLeoRX/opencv-text-deskew/blob/master/python-service/services/deskew_service.py
 // https://becominghuman.ai/how-to-automatically-deskew-straighten-a-text-image-using-opencv-a0c30aed83df
 // https://github.com/milosgajdos/gocv-playground/blob/master/04_Geometric_Transformations/README.md#perspective-transformation
-func (p DefaultProcessor) Process(image *img.Image) (*img.Image, error) {
-	imgPath, err := image.StoreTmp()
-	if err != nil {
-		return nil, errors.Wrap(err, "storing the image to a temp file")
-	}
-	defer os.Remove(imgPath)
-
-	cvImg := gocv.IMRead(imgPath, gocv.IMReadColor)
-	storeDebug(&cvImg, "1-original")
-
-	convertToGrayscale(&cvImg)
-	storeDebug(&cvImg, "2-grayscale")
-
-	deskew(&cvImg)
-
-	_ = gocv.Threshold(cvImg, &cvImg, 127, 255, gocv.ThresholdBinary+gocv.ThresholdOtsu)
-	storeDebug(&cvImg, "12-black-and-white")
-
-	// tesseract likes borders:
-	// https://tesseract-ocr.github.io/tessdoc/ImproveQuality#dilation-and-erosion
-	gocv.CopyMakeBorder(cvImg, &cvImg, 10, 10, 10, 10, gocv.BorderConstant, color.RGBA{100, 100, 100, 255})
-	storeDebug(&cvImg, "13-withborder")
-
-	result, err := cvImg.ToImage()
+func NewDefaultProcessor() PipelineProcessor {
+	p, err := NewProcessorFromSpec(DefaultStages)
 	if err != nil {
-		return nil, errors.Wrap(err, "converting Mat to image")
+		panic(err)
 	}
-	image.Object = result
 
-	return image, nil
+	return p
 }
 
 func convertToGrayscale(i *gocv.Mat) {
+	// Images that are already grayscale (e.g. scans) have a single channel
+	if i.Channels() == 1 {
+		return
+	}
 	gocv.CvtColor(*i, i, gocv.ColorBGRToGray)
 }
 
-func deskew(i *gocv.Mat) {
+// deskew rotates the image so that the biggest block of text is aligned and,
+// if crop is true, crops the image to that block. It returns how the pixels
+// were moved. It returns ErrNoTextFound, leaving the image untouched, if there
+// is no block of text.
+func deskew(rec *debug.Recorder, i *gocv.Mat, crop bool) (geom.Transform, error) {
 	tmpImg := i.Clone()
 	defer tmpImg.Close()
 
 	// TODO: Blurring doesn't seem to improve things. Maybe it would work with
 	// different Thresholding below.
 	// gocv.GaussianBlur(tmpImg, &tmpImg, goimage.Point{}, 1, 1, gocv.BorderDefault)
-	// storeDebug(&tmpImg, "3-after-gaussionblur")
+	// storeDebug(rec, &tmpImg, "3-after-gaussionblur")
 
-	_ = gocv.Threshold(tmpImg, &tmpImg, 100, 255, gocv.ThresholdBinaryInv) //+gocv.ThresholdOtsu)
-	storeDebug(&tmpImg, "4-after-threshold")
+	// A local threshold keeps the text even in the shadowed parts of the page
+	gocv.AdaptiveThreshold(tmpImg, &tmpImg, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(tmpImg), 15)
+	storeDebug(rec, &tmpImg, "4-after-threshold")
 
-	// TODO: this is a hack. We decide what the kernel size is based on the resolution
-	// of the image. This means, we assume what the approximate size of each character is
-	// a certain percentage of the total page.
-	kernelWidth := (i.Cols() / 150) / 2
-	kernelHeight := i.Rows() / 150
-	kernel := gocv.GetStructuringElement(gocv.MorphRect, goimage.Point{kernelWidth, kernelHeight})
-	gocv.DilateWithParams(tmpImg, &tmpImg, kernel, goimage.Point{}, 5, gocv.BorderDefault, color.RGBA{})
-	storeDebug(&tmpImg, "5-after-dilate")
+	kernelSize, iterations := blockKernel(*i)
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, kernelSize)
+	defer kernel.Close()
+	gocv.DilateWithParams(tmpImg, &tmpImg, kernel, goimage.Point{}, gocv.BorderType(iterations), gocv.BorderDefault, color.RGBA{})
+	storeDebug(rec, &tmpImg, "5-after-dilate")
 
 	points := gocv.FindContours(tmpImg, gocv.RetrievalList, gocv.ChainApproxSimple)
+	defer points.Close()
 	contours := ContoursBySize{}
-	for j := 1; j < points.Size(); j++ {
+	for j := 0; j < points.Size(); j++ {
 		contours = append(contours, Contour{OriginalIdx: j, Contour: points.At(j)})
 	}
+	if len(contours) == 0 {
+		rec.Notef("deskew", "no contours found")
+		return geom.Identity(), ErrNoTextFound
+	}
 	sort.Sort(contours)
 
 	contouredImage := i.Clone()
@@ -126,7 +163,7 @@ func deskew(i *gocv.Mat) {
 	}
 	maxContour := contours[len(contours)-1]
 	gocv.DrawContours(&contouredImage, points, maxContour.OriginalIdx, color.RGBA{100, 80, 20, 255}, 2)
-	storeDebug(&contouredImage, "6-contoured")
+	storeDebug(rec, &contouredImage, "6-contoured")
 
 	rect := gocv.MinAreaRect(maxContour.Contour)
 
@@ -135,11 +172,16 @@ func deskew(i *gocv.Mat) {
 	defer originalCopy.Close()
 	rectV := gocv.NewPointsVectorFromPoints([][]goimage.Point{rect.Points})
 	gocv.DrawContours(&originalCopy, rectV, -1, color.RGBA{0, 255, 0, 255}, 3)
-	storeDebug(&originalCopy, "7-min-rectangle")
+	storeDebug(rec, &originalCopy, "7-min-rectangle")
 
 	skewAngle := calculateSkewAngle(rect.Angle)
-	rotateImg(i, rect.Center, skewAngle)
-	storeDebug(i, "9-deskew")
+	rec.Notef("deskew", "%d contours, biggest area %.0f, min area rectangle %+v, skew angle %.2f",
+		len(contours), gocv.ContourArea(maxContour.Contour), rect, skewAngle)
+	moved := rotateImg(i, rect.Center, skewAngle)
+	storeDebug(rec, i, "9-deskew")
+	if !crop {
+		return moved, nil
+	}
 
 	// Construct the straight rectangle that contains our text (in the, now deskewed, image)
 	var straightWidth, straightHeight int
@@ -161,7 +203,7 @@ func deskew(i *gocv.Mat) {
 	straightCopy := i.Clone()
 	defer straightCopy.Close()
 	gocv.DrawContours(&straightCopy, straightRectPoints, -1, color.RGBA{255, 255, 255, 255}, 3)
-	storeDebug(&straightCopy, "10-deskewed-min-rectangle")
+	storeDebug(rec, &straightCopy, "10-deskewed-min-rectangle")
 
 	// Now let's crop the rectangle
 	straightRect := goimage.Rectangle{
@@ -180,8 +222,24 @@ func deskew(i *gocv.Mat) {
 	if !croppedMat.IsContinuous() {
 		croppedMat = croppedMat.Clone()
 	}
-	storeDebug(&croppedMat, "11-cropped")
+	storeDebug(rec, &croppedMat, "11-cropped")
 	*i = croppedMat
+
+	return moved.Then(geom.Translation(-float64(straightRect.Min.X), -float64(straightRect.Min.Y))), nil
+}
+
+// blockKernel returns the size of the dilation kernel (and the number of
+// iterations) that joins the characters, the words and the lines of a block
+// of text together. It is based on the estimated size of the text so that
+// both large print and tiny footnotes end up as blocks.
+func blockKernel(i gocv.Mat) (goimage.Point, int) {
+	if m, ok := EstimateTextMetrics(i); ok {
+		return goimage.Point{X: 2 * m.GlyphWidth, Y: m.LineSpacing}, 1
+	}
+
+	// Not enough text to estimate its size. Assume the characters are a
+	// certain percentage of the total page.
+	return goimage.Point{X: (i.Cols() / 150) / 2, Y: i.Rows() / 150}, 5
 }
 
 // calculateSkewAngle take the angle of the min area rectagle and return the
@@ -198,23 +256,52 @@ func calculateSkewAngle(angle float64) float64 {
 	return angle
 }
 
-// Rotate the image around its center
-func rotateImg(i *gocv.Mat, center goimage.Point, angle float64) {
+// Rotate the image around its center. It returns how the pixels were moved.
+func rotateImg(i *gocv.Mat, center goimage.Point, angle float64) geom.Transform {
 	size := i.Size()
 	width := size[1]
 	height := size[0]
 	rMatrix := gocv.GetRotationMatrix2D(center, angle, 1.0)
+	defer rMatrix.Close()
 	gocv.WarpAffineWithParams(*i, i, rMatrix, goimage.Point{X: width, Y: height}, gocv.InterpolationCubic, gocv.BorderReplicate, color.RGBA{0, 0, 0, 0})
+
+	return matTransform(rMatrix)
 }
 
-// storeDebug writes an image to the filesystem if OOR_DEBUG env var is set
-func storeDebug(i *gocv.Mat, filename string) {
-	if os.Getenv("OOR_DEBUG") != "" {
-		outPath := filepath.Join("tmp", filename+".jpg")
-		if ok := gocv.IMWrite(outPath, *i); !ok {
-			panic(fmt.Sprintf("Failed to write image: %s\n", outPath))
+// matTransform returns the Transform of a 2x3 (affine) or 3x3 (perspective)
+// matrix of OpenCV
+func matTransform(m gocv.Mat) geom.Transform {
+	t := geom.Identity()
+	for r := 0; r < m.Rows() && r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			t[3*r+c] = m.GetDoubleAt(r, c)
 		}
 	}
+
+	return t
+}
+
+// drawRegions draws the outline of the regions on the image
+func drawRegions(i *gocv.Mat, regions []layout.Region) {
+	for j, r := range regions {
+		gocv.Rectangle(i, r.Rect, color.RGBA{255, 0, 0, 255}, 3)
+		gocv.PutText(i, strconv.Itoa(j+1), r.Rect.Min.Add(goimage.Point{X: 5, Y: 30}), gocv.FontHersheyPlain, 2, color.RGBA{255, 0, 0, 255}, 2)
+	}
+}
+
+// storeDebug records an image in the debug report of the run, if enabled
+func storeDebug(rec *debug.Recorder, i *gocv.Mat, name string) {
+	if !rec.Enabled() {
+		return
+	}
+
+	buf, err := gocv.IMEncode(gocv.JPEGFileExt, *i)
+	if err != nil {
+		rec.Notef(name, "encoding the image: %s", err.Error())
+		return
+	}
+	defer buf.Close()
+	rec.Image(name, "image/jpeg", buf.GetBytes())
 }
 
 func showImg(i gocv.Mat) {
@@ -0,0 +1,296 @@
+package process
+
+import (
+	goimage "image"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("quality", newQualityStage)
+}
+
+// Problem is something wrong with a photo that the user can fix by taking
+// another one
+type Problem string
+
+const (
+	ProblemBlurry    Problem = "blurry"
+	ProblemTooDark   Problem = "too-dark"
+	ProblemTooBright Problem = "too-bright"
+	ProblemGlare     Problem = "glare"
+	ProblemCutOff    Problem = "cut-off"
+	ProblemTooFar    Problem = "too-far"
+	ProblemFinger    Problem = "finger"
+)
+
+var problemAdvice = map[Problem]string{
+	ProblemBlurry:    "The photo is blurry. Hold the camera still and try again.",
+	ProblemTooDark:   "The photo is too dark. Turn on a light.",
+	ProblemTooBright: "The photo is too bright. Move away from the light.",
+	ProblemGlare:     "There is glare on the page. Tilt the page or the camera a little.",
+	ProblemCutOff:    "The text is cut off at the edge of the photo. Move the camera further away.",
+	ProblemTooFar:    "The text is too small. Move the camera closer.",
+	ProblemFinger:    "A finger is covering some of the text. Hold the book by its edges.",
+}
+
+// Advice returns what the user should do to fix the problem
+func (p Problem) Advice() string {
+	return problemAdvice[p]
+}
+
+// Quality holds the measurements taken on a photo and the problems found
+type Quality struct {
+	// Sharpness is the variance of the Laplacian. Blurry photos have few
+	// edges and a low variance.
+	Sharpness float64
+	// Brightness is the mean intensity (0-255)
+	Brightness float64
+	// Glare is the fraction of the pixels that are saturated
+	Glare float64
+	// EdgeText is the fraction of the frame edge covered by text
+	EdgeText float64
+	// TextArea is the fraction of the frame covered by the text
+	TextArea float64
+	Problems []Problem
+}
+
+// Advice returns what the user should do to fix all the problems
+func (q Quality) Advice() string {
+	advice := []string{}
+	for _, p := range q.Problems {
+		advice = append(advice, p.Advice())
+	}
+
+	return strings.Join(advice, " ")
+}
+
+// QualityError is returned when a photo is not good enough for OCR
+type QualityError struct {
+	Quality Quality
+}
+
+func (e *QualityError) Error() string {
+	problems := []string{}
+	for _, p := range e.Quality.Problems {
+		problems = append(problems, string(p))
+	}
+
+	return "the photo is not good enough for OCR: " + strings.Join(problems, ", ")
+}
+
+// PhotoStage is implemented by stages that judge the photo itself rather
+// than the text in it. When a part of the photo is selected, the pipeline
+// runs them on the whole photo before cropping it and skips them after.
+type PhotoStage interface {
+	Stage
+	WholePhoto()
+}
+
+// QualityStage measures the quality of the photo and stores it in the page,
+// so that the user can be told how to take a better one. The thresholds are
+// rough guesses, so by default the problems are only reported. When Fail is
+// true, it stops the pipeline with a QualityError instead of running OCR on
+// a useless image.
+type QualityStage struct {
+	Fail          bool
+	MinSharpness  float64
+	MinBrightness float64
+	MaxBrightness float64
+	MaxGlare      float64
+	MaxEdgeText   float64
+	MinTextArea   float64
+}
+
+func newQualityStage(params Params) (Stage, error) {
+	var err error
+	s := QualityStage{}
+	if s.Fail, err = params.Bool("fail", false); err != nil {
+		return nil, err
+	}
+	if s.MinSharpness, err = params.Float("min-sharpness", 100); err != nil {
+		return nil, err
+	}
+	if s.MinBrightness, err = params.Float("min-brightness", 60); err != nil {
+		return nil, err
+	}
+	if s.MaxBrightness, err = params.Float("max-brightness", 230); err != nil {
+		return nil, err
+	}
+	if s.MaxGlare, err = params.Float("max-glare", 0.05); err != nil {
+		return nil, err
+	}
+	if s.MaxEdgeText, err = params.Float("max-edge-text", 0.1); err != nil {
+		return nil, err
+	}
+	if s.MinTextArea, err = params.Float("min-text-area", 0.15); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s QualityStage) Name() string { return "quality" }
+
+// WholePhoto makes the pipeline measure the whole photo instead of the
+// selection, which is often small and cuts off the text by design
+func (s QualityStage) WholePhoto() {}
+
+func (s QualityStage) Apply(page *Page) error {
+	q := AssessQuality(page.Mat)
+
+	if q.Sharpness < s.MinSharpness {
+		q.Problems = append(q.Problems, ProblemBlurry)
+	}
+	if q.Brightness < s.MinBrightness {
+		q.Problems = append(q.Problems, ProblemTooDark)
+	}
+	if q.Brightness > s.MaxBrightness {
+		q.Problems = append(q.Problems, ProblemTooBright)
+	}
+	if q.Glare > s.MaxGlare {
+		q.Problems = append(q.Problems, ProblemGlare)
+	}
+	if q.EdgeText > s.MaxEdgeText {
+		q.Problems = append(q.Problems, ProblemCutOff)
+	}
+	if q.TextArea < s.MinTextArea {
+		q.Problems = append(q.Problems, ProblemTooFar)
+	}
+	page.Quality = &q
+	page.Debug.Notef("quality", "%+v", q)
+
+	if s.Fail && len(q.Problems) > 0 {
+		return &QualityError{Quality: q}
+	}
+
+	return nil
+}
+
+// qualityWidth is the width the image is resized to before measuring, to make
+// the measurements independent of the resolution of the camera
+const qualityWidth = 1000
+
+// AssessQuality measures the sharpness, the exposure, the glare and the
+// position of the text in the image. It doesn't decide what is a problem,
+// that's up to the caller.
+func AssessQuality(i gocv.Mat) Quality {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	if i.Channels() > 1 {
+		gocv.CvtColor(i, &gray, gocv.ColorBGRToGray)
+	} else {
+		i.CopyTo(&gray)
+	}
+	gocv.Resize(gray, &gray, goimage.Point{X: qualityWidth, Y: i.Rows() * qualityWidth / i.Cols()}, 0, 0, gocv.InterpolationArea)
+
+	q := Quality{}
+
+	laplacian := gocv.NewMat()
+	defer laplacian.Close()
+	gocv.Laplacian(gray, &laplacian, gocv.MatTypeCV64F, 1, 1, 0, gocv.BorderDefault)
+	mean, stddev := gocv.NewMat(), gocv.NewMat()
+	defer mean.Close()
+	defer stddev.Close()
+	gocv.MeanStdDev(laplacian, &mean, &stddev)
+	q.Sharpness = stddev.GetDoubleAt(0, 0) * stddev.GetDoubleAt(0, 0)
+
+	q.Brightness = gray.Mean().Val1
+
+	saturated := gocv.NewMat()
+	defer saturated.Close()
+	_ = gocv.Threshold(gray, &saturated, 250, 255, gocv.ThresholdBinary)
+	q.Glare = float64(gocv.CountNonZero(saturated)) / float64(saturated.Total())
+
+	text := gocv.NewMat()
+	defer text.Close()
+	gocv.AdaptiveThreshold(gray, &text, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, adaptiveBlockSize(gray), 15)
+	q.EdgeText = edgeTextRatio(text)
+	q.TextArea = textAreaRatio(text)
+
+	return q
+}
+
+// edgeTextRatio returns the fraction of the outermost rows and columns of
+// the text mask that contain text. Text touching the frame means that part
+// of the page is probably outside of the photo.
+func edgeTextRatio(text gocv.Mat) float64 {
+	strip := max(text.Cols()/100, 1)
+	edges := []goimage.Rectangle{
+		goimage.Rect(0, 0, text.Cols(), strip),
+		goimage.Rect(0, text.Rows()-strip, text.Cols(), text.Rows()),
+		goimage.Rect(0, 0, strip, text.Rows()),
+		goimage.Rect(text.Cols()-strip, 0, text.Cols(), text.Rows()),
+	}
+
+	worst := 0.0
+	for _, r := range edges {
+		region := text.Region(r)
+		ratio := float64(gocv.CountNonZero(region)) / float64(r.Dx()*r.Dy())
+		region.Close()
+		if ratio > worst {
+			worst = ratio
+		}
+	}
+
+	// A strip full of text has roughly a third of its pixels set
+	return minFloat(worst*3, 1)
+}
+
+// textAreaRatio returns the fraction of the frame covered by the rectangle
+// that contains the rows and columns with a noticeable amount of text
+func textAreaRatio(text gocv.Mat) float64 {
+	rows := textExtent(text, 1)
+	cols := textExtent(text, 0)
+
+	return float64(rows*cols) / float64(text.Rows()*text.Cols())
+}
+
+// textExtent returns the distance between the first and the last row
+// (dim 1) or column (dim 0) with text
+func textExtent(text gocv.Mat, dim int) int {
+	first, last := textRange(text, dim)
+	if first < 0 {
+		return 0
+	}
+
+	return last - first + 1
+}
+
+// textRange returns the first and the last row (dim=1) or column (dim=0)
+// with text. It returns -1, -1 if there is no text.
+func textRange(text gocv.Mat, dim int) (int, int) {
+	sums := gocv.NewMat()
+	defer sums.Close()
+	gocv.Reduce(text, &sums, dim, gocv.ReduceAvg, gocv.MatTypeCV32F)
+
+	first, last := -1, -1
+	for j := 0; j < sums.Total(); j++ {
+		var v float32
+		if dim == 0 {
+			v = sums.GetFloatAt(0, j)
+		} else {
+			v = sums.GetFloatAt(j, 0)
+		}
+		// More than 2% of the pixels of the row/column are text
+		if v > 255*0.02 {
+			if first < 0 {
+				first = j
+			}
+			last = j
+		}
+	}
+
+	return first, last
+}
+
+// IsQualityError returns the QualityError wrapped in err, if any
+func IsQualityError(err error) (*QualityError, bool) {
+	var qErr *QualityError
+	if errors.As(err, &qErr) {
+		return qErr, true
+	}
+	return nil, false
+}
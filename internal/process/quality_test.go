@@ -0,0 +1,136 @@
+package process_test
+
+import (
+	"image"
+	"image/color"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// textPage returns a grayscale page with lines of black text starting at x
+func textPage(background float64, x int) gocv.Mat {
+	m := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(background, 0, 0, 0), 1400, 1000, gocv.MatTypeCV8U)
+	for y := 150; y < 1300; y += 60 {
+		gocv.PutText(&m, "the quick brown fox jumps", image.Point{X: x, Y: y}, gocv.FontHersheySimplex, 1.5, color.RGBA{A: 255}, 3)
+	}
+
+	return m
+}
+
+var _ = Describe("QualityError", func() {
+	var qErr *QualityError
+
+	BeforeEach(func() {
+		qErr = &QualityError{Quality: Quality{Problems: []Problem{ProblemTooDark, ProblemTooFar}}}
+	})
+
+	It("lists the problems", func() {
+		Expect(qErr.Error()).To(HaveSuffix("too-dark, too-far"))
+	})
+
+	It("is found in wrapped errors", func() {
+		found, ok := IsQualityError(errors.Wrap(qErr, "processing the image"))
+		Expect(ok).To(BeTrue())
+		Expect(found.Quality.Advice()).To(Equal("The photo is too dark. Turn on a light. The text is too small. Move the camera closer."))
+	})
+})
+
+var _ = Describe("AssessQuality", func() {
+	var sharp gocv.Mat
+	var q Quality
+
+	BeforeEach(func() {
+		sharp = textPage(230, 100)
+		q = AssessQuality(sharp)
+	})
+
+	AfterEach(func() {
+		sharp.Close()
+	})
+
+	It("measures a good photo", func() {
+		Expect(q.Brightness).To(BeNumerically(">", 200))
+		Expect(q.Glare).To(BeZero())
+		Expect(q.EdgeText).To(BeZero())
+		Expect(q.TextArea).To(BeNumerically(">", 0.5))
+	})
+
+	It("finds blurry photos less sharp", func() {
+		blurred := gocv.NewMat()
+		defer blurred.Close()
+		gocv.GaussianBlur(sharp, &blurred, image.Point{X: 31, Y: 31}, 10, 10, gocv.BorderDefault)
+
+		Expect(AssessQuality(blurred).Sharpness).To(BeNumerically("<", q.Sharpness/10))
+	})
+
+	It("measures dark photos", func() {
+		dark := textPage(30, 100)
+		defer dark.Close()
+
+		Expect(AssessQuality(dark).Brightness).To(BeNumerically("<", 40))
+	})
+
+	It("finds text on the edge of the photo", func() {
+		cut := textPage(230, 400)
+		defer cut.Close()
+
+		Expect(AssessQuality(cut).EdgeText).To(BeNumerically(">", 0.1))
+	})
+})
+
+var _ = Describe("QualityStage", func() {
+	var stage QualityStage
+
+	BeforeEach(func() {
+		p, err := NewProcessorFromSpec("quality")
+		Expect(err).ToNot(HaveOccurred())
+		stage = p.Stages[0].(QualityStage)
+	})
+
+	It("doesn't stop the pipeline by default", func() {
+		Expect(stage.Fail).To(BeFalse())
+	})
+
+	It("finds no problems on a good photo", func() {
+		m := textPage(230, 100)
+		defer m.Close()
+		page := &Page{Mat: m}
+
+		Expect(stage.Apply(page)).To(Succeed())
+		Expect(page.Quality).ToNot(BeNil())
+		Expect(page.Quality.Problems).To(BeEmpty())
+	})
+
+	It("reports the problems without failing", func() {
+		m := textPage(30, 400)
+		defer m.Close()
+		page := &Page{Mat: m}
+
+		Expect(stage.Apply(page)).To(Succeed())
+		Expect(page.Quality.Problems).To(ContainElements(ProblemTooDark, ProblemCutOff))
+	})
+
+	It("fails on problems when asked to", func() {
+		stage.Fail = true
+		m := textPage(30, 100)
+		defer m.Close()
+
+		qErr, ok := IsQualityError(stage.Apply(&Page{Mat: m}))
+		Expect(ok).To(BeTrue())
+		Expect(qErr.Quality.Problems).To(ContainElement(ProblemTooDark))
+	})
+
+	It("finds blurry photos", func() {
+		m := textPage(230, 100)
+		defer m.Close()
+		gocv.GaussianBlur(m, &m, image.Point{X: 31, Y: 31}, 10, 10, gocv.BorderDefault)
+		page := &Page{Mat: m}
+
+		Expect(stage.Apply(page)).To(Succeed())
+		Expect(page.Quality.Problems).To(ContainElement(ProblemBlurry))
+	})
+})
@@ -0,0 +1,137 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Selection is the part of the photo the user wants to read, as a polygon in
+// the coordinates of the photo. An empty selection is the whole photo.
+type Selection []goimage.Point
+
+// ParseSelection parses a rectangle ("x,y,width,height") or a polygon with
+// at least 3 points ("x1,y1 x2,y2 x3,y3 ..."). An empty string is an empty
+// selection.
+func ParseSelection(s string) (Selection, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	points := strings.Fields(s)
+	if len(points) == 1 {
+		v, err := parseInts(points[0], 4)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing the rectangle %q", s)
+		}
+		if v[2] <= 0 || v[3] <= 0 {
+			return nil, errors.Errorf("the rectangle %q has no area", s)
+		}
+		r := goimage.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3])
+		return Selection{r.Min, {X: r.Max.X, Y: r.Min.Y}, r.Max, {X: r.Min.X, Y: r.Max.Y}}, nil
+	}
+
+	if len(points) < 3 {
+		return nil, errors.Errorf("a polygon needs at least 3 points, got %q", s)
+	}
+	sel := Selection{}
+	for _, p := range points {
+		v, err := parseInts(p, 2)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing the polygon %q", s)
+		}
+		sel = append(sel, goimage.Point{X: v[0], Y: v[1]})
+	}
+
+	return sel, nil
+}
+
+// parseInts parses n comma separated integers
+func parseInts(s string, n int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.Errorf("expected %d comma separated numbers, got %q", n, s)
+	}
+	result := []int{}
+	for _, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+// Bounds returns the smallest rectangle that contains the selection
+func (s Selection) Bounds() goimage.Rectangle {
+	if len(s) == 0 {
+		return goimage.Rectangle{}
+	}
+
+	r := goimage.Rectangle{Min: s[0], Max: s[0]}
+	for _, p := range s[1:] {
+		r.Min.X, r.Min.Y = min(r.Min.X, p.X), min(r.Min.Y, p.Y)
+		r.Max.X, r.Max.Y = max(r.Max.X, p.X), max(r.Max.Y, p.Y)
+	}
+
+	return r
+}
+
+// isRectangle returns true if the selection is a rectangle aligned to the
+// axes, which can be cropped without masking
+func (s Selection) isRectangle() bool {
+	if len(s) != 4 {
+		return false
+	}
+	b := s.Bounds()
+	for _, p := range s {
+		if (p.X != b.Min.X && p.X != b.Max.X) || (p.Y != b.Min.Y && p.Y != b.Max.Y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applySelection crops the image to the selection. Everything outside a
+// polygon selection is painted white. It returns the part of the image that
+// was kept or ErrInvalidSelection if the selection is outside of the image.
+func applySelection(i *gocv.Mat, s Selection) (goimage.Rectangle, error) {
+	bounds := goimage.Rect(0, 0, i.Cols(), i.Rows())
+	if len(s) == 0 {
+		return bounds, nil
+	}
+
+	bounds = s.Bounds().Intersect(bounds)
+	if bounds.Empty() {
+		return bounds, errors.WithStack(ErrInvalidSelection)
+	}
+
+	if !s.isRectangle() {
+		mask := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), i.Rows(), i.Cols(), gocv.MatTypeCV8UC1)
+		defer mask.Close()
+		polygon := gocv.NewPointsVectorFromPoints([][]goimage.Point{s})
+		defer polygon.Close()
+		gocv.FillPoly(&mask, polygon, color.RGBA{255, 255, 255, 255})
+
+		white := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 255, 255, 0), i.Rows(), i.Cols(), i.Type())
+		i.CopyToWithMask(&white, mask)
+		i.Close()
+		*i = white
+	}
+
+	cropped := i.Region(bounds)
+	defer cropped.Close()
+	result := cropped.Clone()
+	i.Close()
+	*i = result
+
+	return bounds, nil
+}
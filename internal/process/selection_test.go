@@ -0,0 +1,38 @@
+package process_test
+
+import (
+	"image"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseSelection", func() {
+	It("parses a rectangle", func() {
+		s, err := ParseSelection("10,20,100,50")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(HaveLen(4))
+		Expect(s.Bounds()).To(Equal(image.Rect(10, 20, 110, 70)))
+	})
+
+	It("parses a polygon", func() {
+		s, err := ParseSelection("10,20 100,30 50,90")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(Selection{{X: 10, Y: 20}, {X: 100, Y: 30}, {X: 50, Y: 90}}))
+		Expect(s.Bounds()).To(Equal(image.Rect(10, 20, 100, 90)))
+	})
+
+	It("returns an empty selection for an empty string", func() {
+		s, err := ParseSelection(" ")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(BeEmpty())
+	})
+
+	It("returns an error for invalid selections", func() {
+		for _, s := range []string{"10,20,0,50", "10,20", "10,20 30,40", "a,b,c,d"} {
+			_, err := ParseSelection(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+	})
+})
@@ -0,0 +1,148 @@
+package process
+
+import (
+	goimage "image"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("split", newSplitStage)
+}
+
+// Splitter is implemented by stages that turn one page into many. The
+// pipeline calls Split instead of Apply on these stages and runs the
+// following stages on every page returned.
+type Splitter interface {
+	Split(page *Page) ([]*Page, error)
+}
+
+// SplitStage detects a two page spread (e.g. a photo of an open book) by
+// looking for the gutter between the pages and splits the image in two.
+// The pages are returned in reading order, which is left to right unless
+// RightToLeft is set.
+type SplitStage struct {
+	RightToLeft bool
+	// MaxGutter is the maximum text density of the gutter as a fraction of
+	// the text density of the pages on either side of it.
+	MaxGutter float64
+}
+
+func newSplitStage(params Params) (Stage, error) {
+	direction := params.String("direction", "ltr")
+	if direction != "ltr" && direction != "rtl" {
+		return nil, errors.Errorf("direction should be ltr or rtl, got %q", direction)
+	}
+	maxGutter, err := params.Float("max-gutter", 0.2)
+	if err != nil {
+		return nil, err
+	}
+
+	return SplitStage{RightToLeft: direction == "rtl", MaxGutter: maxGutter}, nil
+}
+
+func (s SplitStage) Name() string { return "split" }
+
+func (s SplitStage) Apply(page *Page) error {
+	return errors.New("the split stage can only run as part of a pipeline")
+}
+
+func (s SplitStage) Split(page *Page) ([]*Page, error) {
+	gutter, ok := findGutter(page.Debug, page.Mat, s.MaxGutter)
+	if !ok {
+		return []*Page{page}, nil
+	}
+
+	left := page.Mat.Region(goimage.Rect(0, 0, gutter, page.Mat.Rows()))
+	right := page.Mat.Region(goimage.Rect(gutter, 0, page.Mat.Cols(), page.Mat.Rows()))
+	leftPage := page.derive(left.Clone())
+	rightPage := page.derive(right.Clone())
+	rightPage.moved(geom.Translation(-float64(gutter), 0))
+	left.Close()
+	right.Close()
+	page.Mat.Close()
+
+	if s.RightToLeft {
+		return []*Page{rightPage, leftPage}, nil
+	}
+	return []*Page{leftPage, rightPage}, nil
+}
+
+// findGutter looks for the column between two facing pages. It builds the
+// text density of each column of the image and searches the middle of the
+// image for a column with much less text than the pages on its left and
+// right. Returns false if the image doesn't look like a two page spread.
+func findGutter(rec *debug.Recorder, i gocv.Mat, maxGutter float64) (int, bool) {
+	gray := i.Clone()
+	defer gray.Close()
+	if gray.Channels() > 1 {
+		convertToGrayscale(&gray)
+	}
+
+	// Adaptive threshold keeps the text but not the shadow of the gutter
+	gocv.AdaptiveThreshold(gray, &gray, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, 25, 15)
+	storeDebug(rec, &gray, "split-text")
+
+	sums := gocv.NewMat()
+	defer sums.Close()
+	gocv.Reduce(gray, &sums, 0, gocv.ReduceSum, gocv.MatTypeCV32F)
+
+	cols := i.Cols()
+	density := make([]float64, cols)
+	for x := 0; x < cols; x++ {
+		density[x] = float64(sums.GetFloatAt(0, x)) / 255 / float64(i.Rows())
+	}
+	density = movingAverage(density, max(cols/100, 1))
+
+	gutter := cols * 35 / 100
+	for x := gutter; x < cols*65/100; x++ {
+		if density[x] < density[gutter] {
+			gutter = x
+		}
+	}
+
+	left := mean(density[cols/10 : gutter])
+	right := mean(density[gutter : cols*9/10])
+	// Don't split pages with (almost) no text on one side
+	const minDensity = 0.01
+	if left < minDensity || right < minDensity {
+		return 0, false
+	}
+
+	rec.Notef("split", "gutter candidate at x=%d, density %.3f, left page %.3f, right page %.3f", gutter, density[gutter], left, right)
+
+	return gutter, density[gutter] < maxGutter*minFloat(left, right)
+}
+
+// movingAverage smooths the values using a centered window of the given size
+func movingAverage(values []float64, window int) []float64 {
+	result := make([]float64, len(values))
+	for j := range values {
+		from, to := max(j-window/2, 0), min(j+window/2+1, len(values))
+		result[j] = mean(values[from:to])
+	}
+
+	return result
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
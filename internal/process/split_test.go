@@ -0,0 +1,95 @@
+package process_test
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gocv.io/x/gocv"
+)
+
+// sentences are the lines of the pages, different so that the gaps between
+// the words don't line up
+var sentences = []string{
+	"the quick brown fox jumps",
+	"over the lazy dog and runs",
+	"into the woods where it hides",
+	"from the hunters all night",
+}
+
+// spread returns a grayscale photo of the given width with a page of text
+// starting at each of the xs
+func spread(width int, xs ...int) gocv.Mat {
+	m := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(230, 0, 0, 0), 1400, width, gocv.MatTypeCV8U)
+	for _, x := range xs {
+		for j, y := 0, 150; y < 1300; j, y = j+1, y+60 {
+			gocv.PutText(&m, sentences[j%len(sentences)], image.Point{X: x, Y: y}, gocv.FontHersheySimplex, 1.5, color.RGBA{A: 255}, 3)
+		}
+	}
+
+	return m
+}
+
+// textEnd returns where the longest line of a page starting at x ends
+func textEnd(x int) int {
+	end := x
+	for _, s := range sentences {
+		if e := x + gocv.GetTextSize(s, gocv.FontHersheySimplex, 1.5, 3).X; e > end {
+			end = e
+		}
+	}
+
+	return end
+}
+
+var _ = Describe("SplitStage", func() {
+	var pages []*Page
+
+	AfterEach(func() {
+		for _, p := range pages {
+			p.Mat.Close()
+		}
+	})
+
+	It("splits a two page spread at the gutter", func() {
+		page := &Page{Mat: spread(2000, 100, 1150), ToOriginal: geom.Identity()}
+
+		var err error
+		pages, err = SplitStage{MaxGutter: 0.2}.Split(page)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pages).To(HaveLen(2))
+		gutter := pages[0].Mat.Cols()
+		Expect(gutter).To(BeNumerically(">=", textEnd(100)))
+		Expect(gutter).To(BeNumerically("<=", 1150))
+		Expect(pages[1].Mat.Cols()).To(Equal(2000 - gutter))
+		// The right page starts at the gutter of the photo
+		x, _ := pages[1].ToOriginal.Apply(0, 0)
+		Expect(x).To(BeNumerically("==", gutter))
+	})
+
+	It("returns the right page first for right to left books", func() {
+		page := &Page{Mat: spread(2000, 100, 1150), ToOriginal: geom.Identity()}
+
+		var err error
+		pages, err = SplitStage{RightToLeft: true, MaxGutter: 0.2}.Split(page)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pages).To(HaveLen(2))
+		x, _ := pages[0].ToOriginal.Apply(0, 0)
+		Expect(x).To(BeNumerically(">=", textEnd(100)))
+		x, _ = pages[1].ToOriginal.Apply(0, 0)
+		Expect(x).To(BeZero())
+	})
+
+	It("doesn't split a single page", func() {
+		page := &Page{Mat: spread(1000, 100), ToOriginal: geom.Identity()}
+
+		var err error
+		pages, err = SplitStage{MaxGutter: 0.2}.Split(page)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pages).To(HaveLen(1))
+		Expect(pages[0].Mat.Cols()).To(Equal(1000))
+	})
+})
@@ -0,0 +1,216 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/debug"
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// Stage is a single step of the processing pipeline. Stages are applied in
+// order and each one transforms the Page it receives in place.
+type Stage interface {
+	Name() string
+	Apply(page *Page) error
+}
+
+// Page is the state that is passed from one stage to the next.
+type Page struct {
+	Mat gocv.Mat
+	// Orientation is the clockwise rotation (0, 90, 180 or 270 degrees)
+	// applied to the page to make the text upright
+	Orientation int
+	// Quality is set by the quality stage
+	Quality *Quality
+	// Regions are the parts of the page to OCR, in reading order. When
+	// empty, the whole page is OCR'd.
+	Regions []layout.Region
+	// Fingers are set by the fingers stage
+	Fingers []Finger
+	// ToOriginal maps the coordinates of the page to the coordinates of the
+	// photo. The stages that move the pixels (e.g. rotate, crop or scale the
+	// page) update it with moved.
+	ToOriginal geom.Transform
+	// Languages are the tesseract languages of the text and Pool provides
+	// the clients to read it
+	Languages []string
+	Pool      *ocr.Pool
+	// Debug records the intermediate results of the run
+	Debug *debug.Recorder
+}
+
+// derive returns a new page with the given Mat and the metadata of p
+func (p *Page) derive(mat gocv.Mat) *Page {
+	c := *p
+	c.Mat = mat
+	return &c
+}
+
+// moved records that the pixels of the page were moved with m, from the old
+// coordinates to the new ones
+func (p *Page) moved(m geom.Transform) {
+	if inverse, ok := m.Invert(); ok {
+		p.ToOriginal = inverse.Then(p.ToOriginal)
+	}
+}
+
+// StageFactory creates a new Stage using the given parameters
+type StageFactory func(params Params) (Stage, error)
+
+var registry = map[string]StageFactory{}
+
+// RegisterStage makes a stage available to pipelines under the given name.
+// Registering a name twice replaces the previous factory.
+func RegisterStage(name string, factory StageFactory) {
+	registry[name] = factory
+}
+
+// RegisteredStages returns the names of all known stages, sorted
+func RegisteredStages() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewStage creates the stage registered under the given name
+func NewStage(name string, params Params) (Stage, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown stage %q (known stages: %s)", name, strings.Join(RegisteredStages(), ", "))
+	}
+
+	stage, err := factory(params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating stage %q", name)
+	}
+
+	return stage, nil
+}
+
+// Params holds the configuration of a single stage as it was given in the
+// stages specification (e.g. "border(size=20)").
+type Params map[string]string
+
+// String returns the value of the given key or def if it is not set
+func (p Params) String(key, def string) string {
+	if v, ok := p[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Int returns the value of the given key as an int or def if it is not set
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing parameter %q", key)
+	}
+	return i, nil
+}
+
+// Float returns the value of the given key as a float64 or def if it is not set
+func (p Params) Float(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing parameter %q", key)
+	}
+	return f, nil
+}
+
+// Bool returns the value of the given key as a bool or def if it is not set
+func (p Params) Bool(key string, def bool) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Wrapf(err, "parsing parameter %q", key)
+	}
+	return b, nil
+}
+
+// StageSpec is the name and parameters of a stage as read from the config
+type StageSpec struct {
+	Name   string
+	Params Params
+}
+
+// ParseStages parses a comma separated list of stages. Each stage can
+// optionally have parameters in parentheses. E.g.
+//
+//	grayscale,deskew,threshold,border(size=20,color=255)
+func ParseStages(spec string) ([]StageSpec, error) {
+	result := []StageSpec{}
+	for _, part := range splitTopLevel(spec, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		s := StageSpec{Name: part, Params: Params{}}
+		if open := strings.Index(part, "("); open >= 0 {
+			if !strings.HasSuffix(part, ")") {
+				return nil, fmt.Errorf("missing closing parenthesis in stage %q", part)
+			}
+			s.Name = strings.TrimSpace(part[:open])
+			for _, kv := range strings.Split(part[open+1:len(part)-1], ",") {
+				kv = strings.TrimSpace(kv)
+				if kv == "" {
+					continue
+				}
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					return nil, fmt.Errorf("invalid parameter %q in stage %q", kv, s.Name)
+				}
+				s.Params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+		if s.Name == "" {
+			return nil, fmt.Errorf("missing stage name in %q", part)
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside parentheses
+func splitTopLevel(s string, sep rune) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -0,0 +1,55 @@
+package process_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseStages", func() {
+	It("parses stages with and without parameters", func() {
+		specs, err := ParseStages("grayscale, deskew,border(size=20, color=255)")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(specs).To(Equal([]StageSpec{
+			{Name: "grayscale", Params: Params{}},
+			{Name: "deskew", Params: Params{}},
+			{Name: "border", Params: Params{"size": "20", "color": "255"}},
+		}))
+	})
+
+	It("returns an error on unbalanced parentheses", func() {
+		_, err := ParseStages("border(size=20")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("NewProcessorFromSpec", func() {
+	It("builds the stages in order", func() {
+		p, err := NewProcessorFromSpec(DefaultStages)
+		Expect(err).ToNot(HaveOccurred())
+		names := []string{}
+		for _, s := range p.Stages {
+			names = append(names, s.Name())
+		}
+		Expect(names).To(Equal([]string{"quality", "grayscale", "orient", "split", "deskew", "margins", "upscale", "threshold", "border"}))
+	})
+
+	It("returns an error for invalid parameters", func() {
+		_, err := NewProcessorFromSpec("upscale(max-scale=0.5)")
+		Expect(err).To(MatchError(ContainSubstring(`creating stage "upscale"`)))
+	})
+
+	It("returns an error for unknown stages", func() {
+		_, err := NewProcessorFromSpec("grayscale,sharpen")
+		Expect(err).To(MatchError(ContainSubstring(`unknown stage "sharpen"`)))
+	})
+})
+
+var _ = Describe("Profiles", func() {
+	It("are all valid", func() {
+		for name, spec := range Profiles {
+			_, err := NewProcessorFromSpec(spec)
+			Expect(err).ToNot(HaveOccurred(), name)
+		}
+	})
+})
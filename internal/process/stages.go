@@ -0,0 +1,102 @@
+package process
+
+import (
+	goimage "image"
+	"image/color"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+// The built-in stages. These are the steps the processor always did before
+// it became configurable.
+func init() {
+	RegisterStage("grayscale", func(Params) (Stage, error) { return GrayscaleStage{}, nil })
+	RegisterStage("deskew", newDeskewStage)
+	RegisterStage("border", newBorderStage)
+}
+
+// GrayscaleStage converts the image to grayscale
+type GrayscaleStage struct{}
+
+func (s GrayscaleStage) Name() string { return "grayscale" }
+
+func (s GrayscaleStage) Apply(page *Page) error {
+	convertToGrayscale(&page.Mat)
+	return nil
+}
+
+// DeskewStage finds the biggest block of text, rotates the image so that
+// the block is aligned and crops the image to it. With Crop set to false the
+// image is only rotated, which keeps the rest of the page (e.g. the other
+// columns) for the layout stage.
+type DeskewStage struct {
+	Crop bool
+}
+
+func newDeskewStage(params Params) (Stage, error) {
+	crop, err := params.Bool("crop", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeskewStage{Crop: crop}, nil
+}
+
+func (s DeskewStage) Name() string { return "deskew" }
+
+func (s DeskewStage) Apply(page *Page) error {
+	moved, err := deskew(page.Debug, &page.Mat, s.Crop)
+	if errors.Is(err, ErrNoTextFound) {
+		// Process the whole frame
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	page.moved(moved)
+
+	return nil
+}
+
+// BorderStage adds a constant border around the image.
+// tesseract likes borders:
+// https://tesseract-ocr.github.io/tessdoc/ImproveQuality#dilation-and-erosion
+type BorderStage struct {
+	// Size is the width of the border. When 0, it is the height of the
+	// characters (but at least 10 pixels).
+	Size  int
+	Color color.RGBA
+}
+
+func newBorderStage(params Params) (Stage, error) {
+	size, err := params.Int("size", 0)
+	if err != nil {
+		return nil, err
+	}
+	c, err := params.Int("color", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	return BorderStage{Size: size, Color: color.RGBA{uint8(c), uint8(c), uint8(c), 255}}, nil
+}
+
+func (s BorderStage) Name() string { return "border" }
+
+func (s BorderStage) Apply(page *Page) error {
+	size := s.Size
+	if size == 0 {
+		size = 10
+		if m, ok := EstimateTextMetrics(page.Mat); ok {
+			size = max(m.GlyphHeight, size)
+		}
+	}
+	gocv.CopyMakeBorder(page.Mat, &page.Mat, size, size, size, size, gocv.BorderConstant, s.Color)
+	for j := range page.Regions {
+		page.Regions[j].Rect = page.Regions[j].Rect.Add(goimage.Point{X: size, Y: size})
+	}
+	page.moved(geom.Translation(float64(size), float64(size)))
+	return nil
+}
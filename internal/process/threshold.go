@@ -0,0 +1,148 @@
+package process
+
+import (
+	goimage "image"
+	"math"
+
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("threshold", newThresholdStage)
+}
+
+// ThresholdStage makes the image black and white. The method can be:
+//   - otsu: a single global threshold calculated with Otsu's method
+//   - adaptive: a threshold calculated from the mean of the neighbourhood of
+//     each pixel
+//   - sauvola: a threshold calculated from the mean and the standard
+//     deviation of the neighbourhood of each pixel (Sauvola's method)
+//
+// The local methods work better on photos with uneven illumination.
+type ThresholdStage struct {
+	Method string
+	// BlockSize is the size of the neighbourhood used by the local methods.
+	// When 0, it is calculated from the image size.
+	BlockSize int
+	// C is subtracted from the mean by the adaptive method
+	C float64
+	// K and R are the parameters of Sauvola's method
+	K float64
+	R float64
+}
+
+func newThresholdStage(params Params) (Stage, error) {
+	var err error
+	s := ThresholdStage{Method: params.String("method", "otsu")}
+	if s.Method != "otsu" && s.Method != "adaptive" && s.Method != "sauvola" {
+		return nil, errors.Errorf("method should be otsu, adaptive or sauvola, got %q", s.Method)
+	}
+	if s.BlockSize, err = params.Int("block", 0); err != nil {
+		return nil, err
+	}
+	if s.BlockSize != 0 && (s.BlockSize < 3 || s.BlockSize%2 == 0) {
+		return nil, errors.New("block should be an odd number greater than 1")
+	}
+	if s.C, err = params.Float("c", 10); err != nil {
+		return nil, err
+	}
+	if s.K, err = params.Float("k", 0.2); err != nil {
+		return nil, err
+	}
+	if s.R, err = params.Float("r", 128); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s ThresholdStage) Name() string { return "threshold" }
+
+func (s ThresholdStage) Apply(page *Page) error {
+	if page.Mat.Channels() > 1 {
+		convertToGrayscale(&page.Mat)
+	}
+
+	blockSize := s.BlockSize
+	if blockSize == 0 {
+		blockSize = adaptiveBlockSize(page.Mat)
+	}
+
+	switch s.Method {
+	case "adaptive":
+		gocv.AdaptiveThreshold(page.Mat, &page.Mat, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinary, blockSize, float32(s.C))
+	case "sauvola":
+		return sauvolaThreshold(&page.Mat, blockSize, s.K, s.R)
+	default:
+		_ = gocv.Threshold(page.Mat, &page.Mat, 127, 255, gocv.ThresholdBinary+gocv.ThresholdOtsu)
+	}
+
+	return nil
+}
+
+// sauvolaThreshold applies Sauvola's local thresholding:
+//
+//	T = mean * (1 + k * (stddev / r - 1))
+//
+// where mean and stddev are calculated over a window around each pixel.
+// https://en.wikipedia.org/wiki/Thresholding_(image_processing)
+func sauvolaThreshold(i *gocv.Mat, window int, k, r float64) error {
+	imgF := gocv.NewMat()
+	defer imgF.Close()
+	i.ConvertTo(&imgF, gocv.MatTypeCV32F)
+
+	squared := gocv.NewMat()
+	defer squared.Close()
+	gocv.Multiply(imgF, imgF, &squared)
+
+	ksize := goimage.Point{X: window, Y: window}
+	mean := gocv.NewMat()
+	defer mean.Close()
+	gocv.Blur(imgF, &mean, ksize)
+	squaredMean := gocv.NewMat()
+	defer squaredMean.Close()
+	gocv.Blur(squared, &squaredMean, ksize)
+
+	pixels, err := imgF.DataPtrFloat32()
+	if err != nil {
+		return errors.Wrap(err, "accessing the image data")
+	}
+	means, err := mean.DataPtrFloat32()
+	if err != nil {
+		return errors.Wrap(err, "accessing the mean data")
+	}
+	squaredMeans, err := squaredMean.DataPtrFloat32()
+	if err != nil {
+		return errors.Wrap(err, "accessing the squared mean data")
+	}
+
+	result := gocv.NewMatWithSize(i.Rows(), i.Cols(), gocv.MatTypeCV8UC1)
+	out, err := result.DataPtrUint8()
+	if err != nil {
+		result.Close()
+		return errors.Wrap(err, "accessing the result data")
+	}
+	for j, p := range pixels {
+		m := float64(means[j])
+		variance := float64(squaredMeans[j]) - m*m
+		stddev := 0.0
+		if variance > 0 {
+			stddev = math.Sqrt(variance)
+		}
+		if float64(p) > m*(1+k*(stddev/r-1)) {
+			out[j] = 255
+		}
+	}
+
+	i.Close()
+	*i = result
+
+	return nil
+}
+
+// adaptiveBlockSize returns a neighbourhood size for the local thresholding
+// methods based on the size of the image
+func adaptiveBlockSize(i gocv.Mat) int {
+	return odd(max(i.Cols()/40, 15))
+}
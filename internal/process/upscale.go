@@ -0,0 +1,97 @@
+package process
+
+import (
+	goimage "image"
+	"math"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/geom"
+	"github.com/jimmykarily/open-ocr-reader/internal/layout"
+	"github.com/pkg/errors"
+	"gocv.io/x/gocv"
+)
+
+func init() {
+	RegisterStage("upscale", newUpscaleStage)
+}
+
+// UpscaleStage enlarges pages with small text (e.g. webcam frames) so that
+// the characters are about as tall as in a 300 DPI scan, which is what
+// tesseract is trained on. The whole page is scaled by the factor its median
+// character needs, together with its text regions, because the regions are
+// read from the page. Images that are already large are never upscaled.
+// It should run before the threshold stage so that the interpolation smooths
+// the edges of the characters instead of the edges of the pixels.
+type UpscaleStage struct {
+	// GlyphHeight is the wanted height of the characters, in pixels
+	GlyphHeight int
+	// MaxScale is the maximum factor the page is enlarged by
+	MaxScale float64
+	// MaxSize is the maximum length of the longest side of the page, in
+	// pixels. Pages that are already longer are left untouched.
+	MaxSize int
+}
+
+func newUpscaleStage(params Params) (Stage, error) {
+	var err error
+	s := UpscaleStage{}
+	if s.GlyphHeight, err = params.Int("glyph-height", 30); err != nil {
+		return nil, err
+	}
+	if s.MaxScale, err = params.Float("max-scale", 4); err != nil {
+		return nil, err
+	}
+	if s.MaxSize, err = params.Int("max-size", 4000); err != nil {
+		return nil, err
+	}
+	if s.GlyphHeight <= 0 || s.MaxScale < 1 || s.MaxSize <= 0 {
+		return nil, errors.New("glyph-height and max-size should be positive and max-scale at least 1")
+	}
+
+	return s, nil
+}
+
+func (s UpscaleStage) Name() string { return "upscale" }
+
+func (s UpscaleStage) Apply(page *Page) error {
+	m, ok := EstimateTextMetrics(page.Mat)
+	if !ok {
+		return nil
+	}
+
+	scale := s.scale(m.GlyphHeight, max(page.Mat.Cols(), page.Mat.Rows()))
+	page.Debug.Notef("upscale", "glyph height %d, scale %.2f", m.GlyphHeight, scale)
+	if scale <= 1 {
+		return nil
+	}
+
+	// Lanczos keeps the edges of the characters sharp
+	gocv.Resize(page.Mat, &page.Mat, goimage.Point{}, scale, scale, gocv.InterpolationLanczos4)
+	for j, r := range page.Regions {
+		page.Regions[j] = layout.Region{Rect: scaleRect(r.Rect, scale), Kind: r.Kind}
+	}
+	page.moved(geom.Scaling(scale, scale))
+
+	return nil
+}
+
+// scale returns the factor to enlarge a page by, given the height of its
+// characters and the length of its longest side
+func (s UpscaleStage) scale(glyphHeight, size int) float64 {
+	if glyphHeight >= s.GlyphHeight || size >= s.MaxSize {
+		return 1
+	}
+
+	scale := float64(s.GlyphHeight) / float64(glyphHeight)
+	scale = math.Min(scale, s.MaxScale)
+	scale = math.Min(scale, float64(s.MaxSize)/float64(size))
+
+	return scale
+}
+
+// scaleRect multiplies the coordinates of the rectangle by scale
+func scaleRect(r goimage.Rectangle, scale float64) goimage.Rectangle {
+	return goimage.Rect(
+		int(float64(r.Min.X)*scale), int(float64(r.Min.Y)*scale),
+		int(math.Ceil(float64(r.Max.X)*scale)), int(math.Ceil(float64(r.Max.Y)*scale)),
+	)
+}
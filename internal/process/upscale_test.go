@@ -0,0 +1,24 @@
+package process_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/process"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpscaleStage", func() {
+	stage := UpscaleStage{GlyphHeight: 30, MaxScale: 4, MaxSize: 4000}
+
+	DescribeTable("scale",
+		func(glyphHeight, size int, expected float64) {
+			Expect(stage.Scale(glyphHeight, size)).To(BeNumerically("~", expected, 0.001))
+		},
+		Entry("text tall enough", 30, 1280, 1.0),
+		Entry("text taller than needed", 45, 1280, 1.0),
+		Entry("small text", 15, 1280, 2.0),
+		Entry("tiny text is limited by max-scale", 5, 800, 4.0),
+		Entry("large images are limited by max-size", 10, 2000, 2.0),
+		Entry("images at max-size are never upscaled", 10, 4000, 1.0),
+		Entry("images over max-size are never upscaled", 10, 6000, 1.0),
+	)
+})
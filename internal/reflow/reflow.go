@@ -0,0 +1,165 @@
+// Package reflow is responsible for turning the text of tesseract, which
+// keeps the line breaks and the hyphenation of the printed page, into
+// running text that can be read out loud.
+package reflow
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+)
+
+// hyphens are the characters a word can be split with at the end of a line:
+// the hyphen-minus, the soft hyphen and the unicode hyphen
+const hyphens = "-\u00ad\u2010"
+
+// Text joins the lines of every paragraph and rejoins the words that were
+// hyphenated at the end of a line. Paragraphs are separated by empty lines,
+// like in the output of tesseract, and they are kept. Lines that start a list
+// item (e.g. "• milk" or "2. eggs") stay on their own line.
+//
+// The words are used to tell a word split at the end of a line ("impor-
+// tant") from a hyphenated compound ("well-known"). It can be nil.
+func Text(text string, words *wordlist.Wordlist) string {
+	pars := []string{}
+	for _, par := range paragraphs(text) {
+		pars = append(pars, Paragraph(par, words))
+	}
+
+	return strings.Join(pars, "\n\n")
+}
+
+// Paragraph joins the lines of a single paragraph
+func Paragraph(lines []string, words *wordlist.Wordlist) string {
+	result := ""
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		switch {
+		case result == "":
+			result = line
+		case isListItem(line):
+			result += "\n" + line
+		default:
+			result = joinLines(result, line, words)
+		}
+	}
+
+	return result
+}
+
+// paragraphs splits the text on the empty lines
+func paragraphs(text string) [][]string {
+	result := [][]string{}
+	current := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				result = append(result, current)
+				current = []string{}
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+
+	return result
+}
+
+// joinLines appends the next line to the text, rejoining a word hyphenated
+// across the two
+func joinLines(text, next string, words *wordlist.Wordlist) string {
+	last, size := utf8.DecodeLastRuneInString(text)
+	if !strings.ContainsRune(hyphens, last) {
+		return text + " " + next
+	}
+
+	head := text[:len(text)-size]
+	prefix := lastWord(head)
+	suffix := firstWord(next)
+	if prefix == "" || suffix == "" {
+		return text + " " + next
+	}
+
+	if keepHyphen(prefix, suffix, last, words) {
+		return head + "-" + next
+	}
+
+	return head + next
+}
+
+// keepHyphen returns true if the two parts of a word split at the end of a
+// line are a hyphenated compound rather than a single word
+func keepHyphen(prefix, suffix string, hyphen rune, words *wordlist.Wordlist) bool {
+	// A soft hyphen is only ever printed when a word is split
+	if hyphen == '\u00ad' {
+		return false
+	}
+	if words.Contains(prefix + suffix) {
+		return false
+	}
+	if words.Contains(prefix + "-" + suffix) {
+		return true
+	}
+	// E.g. "Anglo-Saxon"
+	if r, _ := utf8.DecodeRuneInString(suffix); unicode.IsUpper(r) {
+		return true
+	}
+	if words.Contains(prefix) && words.Contains(suffix) {
+		return true
+	}
+
+	// Typesetters split words that are too long far more often than
+	// compounds happen to end up at the end of a line
+	return false
+}
+
+// lastWord returns the letters at the end of the text, e.g. "impor" for
+// "very impor"
+func lastWord(text string) string {
+	end := len(text)
+	start := end
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		start -= size
+	}
+
+	return text[start:end]
+}
+
+// firstWord returns the letters at the start of the text, e.g. "tant" for
+// "tant, they said"
+func firstWord(text string) string {
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		return text
+	}
+
+	return text[:end]
+}
+
+// isListItem returns true if the line starts with a bullet or a number
+// followed by a dot or a parenthesis
+func isListItem(line string) bool {
+	r, size := utf8.DecodeRuneInString(line)
+	if strings.ContainsRune("•‣◦▪–—*", r) {
+		return len(line) > size && line[size] == ' '
+	}
+
+	digits := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits < 1 || digits+1 >= len(line) {
+		return false
+	}
+
+	return (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' '
+}
@@ -0,0 +1,13 @@
+package reflow_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReflow(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Reflow Suite")
+}
@@ -0,0 +1,45 @@
+package reflow_test
+
+import (
+	. "github.com/jimmykarily/open-ocr-reader/internal/reflow"
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Text", func() {
+	words := wordlist.New("important", "well-known", "the", "storm", "sea")
+
+	It("joins the lines of a paragraph and keeps the paragraphs", func() {
+		text := "The storm was\ncoming from   the sea.\n\nIt was late.\n"
+		Expect(Text(text, words)).To(Equal("The storm was coming from the sea.\n\nIt was late."))
+	})
+
+	It("rejoins the words split at the end of a line", func() {
+		Expect(Text("a very impor-\ntant day", words)).To(Equal("a very important day"))
+		Expect(Text("a very impor\u00ad\ntant day", nil)).To(Equal("a very important day"))
+	})
+
+	It("keeps the hyphen of compounds", func() {
+		Expect(Text("a well-\nknown fact", words)).To(Equal("a well-known fact"))
+		Expect(Text("the storm-\nsea", words)).To(Equal("the storm-sea"))
+		Expect(Text("the Anglo-\nSaxon kings", nil)).To(Equal("the Anglo-Saxon kings"))
+	})
+
+	It("rejoins unknown words split at the end of a line", func() {
+		Expect(Text("the ocea-\nnographer", nil)).To(Equal("the oceanographer"))
+	})
+
+	It("doesn't join dashes", func() {
+		Expect(Text("the storm -\nand the sea", nil)).To(Equal("the storm - and the sea"))
+	})
+
+	It("keeps the list items on their own line", func() {
+		text := "You will need:\n• flour\n• eggs and\nmilk\n2. Mix them"
+		Expect(Text(text, nil)).To(Equal("You will need:\n• flour\n• eggs and milk\n2. Mix them"))
+	})
+
+	It("returns nothing for blank text", func() {
+		Expect(Text(" \n\n", nil)).To(Equal(""))
+	})
+})
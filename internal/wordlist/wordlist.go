@@ -0,0 +1,280 @@
+// Package wordlist is responsible for the lists of known words of each
+// language. They are used to tell real words from OCR errors.
+package wordlist
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Wordlist is a set of known words. A nil Wordlist is valid and knows no
+// words.
+type Wordlist struct {
+	words   map[string]struct{}
+	letters map[rune]struct{}
+}
+
+// New returns a Wordlist with the given words
+func New(words ...string) *Wordlist {
+	w := &Wordlist{words: map[string]struct{}{}, letters: map[rune]struct{}{}}
+	w.Add(words...)
+
+	return w
+}
+
+// Read reads a Wordlist with one word per line
+func Read(r io.Reader) (*Wordlist, error) {
+	w := New()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		w.Add(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading the words")
+	}
+
+	return w, nil
+}
+
+// Add adds the words to the list
+func (w *Wordlist) Add(words ...string) {
+	for _, word := range words {
+		if word = Normalize(word); word != "" {
+			w.add(word)
+		}
+	}
+}
+
+func (w *Wordlist) add(word string) {
+	w.words[word] = struct{}{}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			w.letters[r] = struct{}{}
+		}
+	}
+}
+
+// Merge adds all the words of other to the list
+func (w *Wordlist) Merge(other *Wordlist) {
+	if other == nil {
+		return
+	}
+	for word := range other.words {
+		w.add(word)
+	}
+}
+
+// Contains returns true if the word is in the list. Case and the punctuation
+// around the word are ignored.
+func (w *Wordlist) Contains(word string) bool {
+	if w == nil {
+		return false
+	}
+	_, ok := w.words[Normalize(word)]
+
+	return ok
+}
+
+// Len returns the number of words in the list
+func (w *Wordlist) Len() int {
+	if w == nil {
+		return 0
+	}
+
+	return len(w.words)
+}
+
+// Letters returns the letters the words are written with, sorted
+func (w *Wordlist) Letters() []rune {
+	if w == nil {
+		return nil
+	}
+	letters := []rune{}
+	for r := range w.letters {
+		letters = append(letters, r)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+
+	return letters
+}
+
+// Ratio returns the fraction of the words that are in the list. Words
+// without letters (e.g. numbers) are not counted. It returns 0 if there are
+// no words.
+func (w *Wordlist) Ratio(words []string) float64 {
+	known, total := 0, 0
+	for _, word := range words {
+		if Normalize(word) == "" {
+			continue
+		}
+		total++
+		if w.Contains(word) {
+			known++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return float64(known) / float64(total)
+}
+
+// Normalize returns the word in lower case without the punctuation around
+// it. It returns an empty string if the word has no letters.
+func Normalize(word string) string {
+	word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+
+	return strings.ToLower(word)
+}
+
+// Dir returns the directory with the wordlists, one "<language>.txt" file per
+// tesseract language (e.g. "eng.txt"). It is set with the OOR_WORDLIST_DIR
+// environment variable.
+func Dir() string {
+	if dir := os.Getenv("OOR_WORDLIST_DIR"); dir != "" {
+		return dir
+	}
+
+	return "wordlists"
+}
+
+// PersonalPath returns the file of the personal dictionary, the words the
+// user added because the wordlists of the languages don't have them (e.g.
+// names). It is set with the OOR_PERSONAL_DICT environment variable and it is
+// "personal.txt" in Dir by default.
+func PersonalPath() string {
+	if path := os.Getenv("OOR_PERSONAL_DICT"); path != "" {
+		return path
+	}
+
+	return filepath.Join(Dir(), "personal.txt")
+}
+
+// Personal returns the words of the personal dictionary. It is read every
+// time, so that new words are used right away. An empty list is returned if
+// the user hasn't added any words.
+func Personal() (*Wordlist, error) {
+	w, err := readFile(PersonalPath())
+	if os.IsNotExist(errors.Cause(err)) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the personal dictionary")
+	}
+
+	return w, nil
+}
+
+// AddPersonal adds the words to the personal dictionary
+func AddPersonal(words ...string) error {
+	path := PersonalPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "creating the directory of the personal dictionary")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "opening the personal dictionary")
+	}
+	defer f.Close()
+
+	for _, word := range words {
+		if word = Normalize(word); word == "" {
+			continue
+		}
+		if _, err := f.WriteString(word + "\n"); err != nil {
+			return errors.Wrap(err, "writing to the personal dictionary")
+		}
+	}
+
+	return f.Close()
+}
+
+// systemWordlists are used when there is no wordlist for a language in Dir
+var systemWordlists = map[string]string{
+	"eng": "/usr/share/dict/words",
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*Wordlist{}
+	// merged are the wordlists of several languages, by their languages
+	// joined with "+"
+	merged = map[string]*Wordlist{}
+)
+
+// ForLanguages returns the words of all the given languages or nil if there
+// is no wordlist for any of them. The wordlists are read and merged once and
+// cached, so the Wordlist returned is shared and must not be changed.
+func ForLanguages(languages []string) *Wordlist {
+	key := strings.Join(languages, "+")
+	cacheMu.Lock()
+	w, ok := merged[key]
+	cacheMu.Unlock()
+	if ok {
+		return w
+	}
+
+	lists := []*Wordlist{}
+	for _, lang := range languages {
+		if w := forLanguage(lang); w != nil {
+			lists = append(lists, w)
+		}
+	}
+	switch len(lists) {
+	case 0:
+		return nil
+	case 1:
+		return lists[0]
+	}
+
+	w = New()
+	for _, list := range lists {
+		w.Merge(list)
+	}
+	cacheMu.Lock()
+	merged[key] = w
+	cacheMu.Unlock()
+
+	return w
+}
+
+func forLanguage(lang string) *Wordlist {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if w, ok := cache[lang]; ok {
+		return w
+	}
+
+	var w *Wordlist
+	for _, path := range []string{filepath.Join(Dir(), lang+".txt"), systemWordlists[lang]} {
+		if path == "" {
+			continue
+		}
+		if loaded, err := readFile(path); err == nil {
+			w = loaded
+			break
+		}
+	}
+	cache[lang] = w
+
+	return w
+}
+
+func readFile(path string) (*Wordlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
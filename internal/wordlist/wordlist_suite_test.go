@@ -0,0 +1,13 @@
+package wordlist_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWordlist(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wordlist Suite")
+}
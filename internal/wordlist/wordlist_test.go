@@ -0,0 +1,86 @@
+package wordlist_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wordlist", func() {
+	It("ignores case and punctuation", func() {
+		w, err := Read(strings.NewReader("Storm\nsea\n\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Len()).To(Equal(2))
+		Expect(w.Contains("storm,")).To(BeTrue())
+		Expect(w.Contains("(SEA)")).To(BeTrue())
+		Expect(w.Contains("stonn")).To(BeFalse())
+	})
+
+	It("returns the fraction of known words", func() {
+		w := New("the", "storm")
+		Expect(w.Ratio([]string{"The", "stonn", "12", "storm."})).To(BeNumerically("~", 2.0/3))
+		Expect(w.Ratio(nil)).To(Equal(0.0))
+	})
+
+	It("knows no words when nil", func() {
+		var w *Wordlist
+		Expect(w.Contains("the")).To(BeFalse())
+		Expect(w.Len()).To(Equal(0))
+	})
+
+	It("returns the letters of the words", func() {
+		Expect(New("bad", "cab.").Letters()).To(Equal([]rune("abcd")))
+	})
+})
+
+var _ = Describe("ForLanguages", func() {
+	It("reads the wordlists of the languages from the directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "deu.txt"), []byte("sturm\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "ell.txt"), []byte("καταιγίδα\n"), 0644)).To(Succeed())
+		os.Setenv("OOR_WORDLIST_DIR", dir)
+		defer os.Unsetenv("OOR_WORDLIST_DIR")
+
+		w := ForLanguages([]string{"deu", "ell", "xxx"})
+		Expect(w.Contains("Sturm")).To(BeTrue())
+		Expect(w.Contains("καταιγίδα")).To(BeTrue())
+		Expect(ForLanguages([]string{"xxx"})).To(BeNil())
+	})
+
+	It("reads and merges the wordlists once", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "fra.txt"), []byte("tempête\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "ita.txt"), []byte("tempesta\n"), 0644)).To(Succeed())
+		os.Setenv("OOR_WORDLIST_DIR", dir)
+		defer os.Unsetenv("OOR_WORDLIST_DIR")
+
+		Expect(ForLanguages([]string{"fra", "ita"})).To(BeIdenticalTo(ForLanguages([]string{"fra", "ita"})))
+		// A single wordlist isn't copied
+		Expect(ForLanguages([]string{"fra", "yyy"})).To(BeIdenticalTo(ForLanguages([]string{"fra"})))
+	})
+})
+
+var _ = Describe("Personal", func() {
+	BeforeEach(func() {
+		os.Setenv("OOR_PERSONAL_DICT", filepath.Join(GinkgoT().TempDir(), "dict", "personal.txt"))
+		DeferCleanup(os.Unsetenv, "OOR_PERSONAL_DICT")
+	})
+
+	It("is empty until words are added", func() {
+		w, err := Personal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Len()).To(Equal(0))
+
+		Expect(AddPersonal("Karamazov,", "42")).To(Succeed())
+		Expect(AddPersonal("Alyosha")).To(Succeed())
+		w, err = Personal()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Len()).To(Equal(2))
+		Expect(w.Contains("karamazov")).To(BeTrue())
+		Expect(w.Contains("Alyosha")).To(BeTrue())
+	})
+})
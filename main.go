@@ -1,17 +1,23 @@
 package main
 
 import (
+	"expvar"
+	"io"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"github.com/jimmykarily/open-ocr-reader/controllers"
+	"github.com/jimmykarily/open-ocr-reader/internal/export"
 	"github.com/jimmykarily/open-ocr-reader/internal/logger"
 	"github.com/jimmykarily/open-ocr-reader/internal/ocr"
 	"github.com/jimmykarily/open-ocr-reader/internal/oor"
 	"github.com/jimmykarily/open-ocr-reader/internal/process"
 	"github.com/jimmykarily/open-ocr-reader/internal/tts"
 	"github.com/jimmykarily/open-ocr-reader/internal/version"
+	"github.com/jimmykarily/open-ocr-reader/internal/wordlist"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/gorilla/mux"
@@ -26,27 +32,118 @@ var rootCmd = &cobra.Command{
 }
 
 var parseCmd = &cobra.Command{
-	Use:           "parse <image-file>",
-	Short:         "parse an image file of text and produce audio in the command line",
-	Long:          `This command can be as a cli to produce audio from an image file`,
+	Use:   "parse <image-file>",
+	Short: "parse an image file of text and produce audio in the command line",
+	Long: `This command can be as a cli to produce audio from an image file.
+
+The photo is processed with the stages of the OOR_PROFILE environment
+variable ("default", "shadows", "columns" or "handheld") or the ones listed in
+OOR_STAGES. The default stages read the biggest block of text on the page, use
+the "columns" profile for magazines, newspapers and other pages with more
+than one column of text.`,
 	SilenceErrors: true,
 	Args:          cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		logger := logger.New()
 		//logger.Logf("args = %+v\n", args)
 
+		stages, err := process.StagesFromEnv()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		processor, err := process.NewProcessorFromSpec(stages)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		selection, err := process.ParseSelection(parseSelection)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		languages := ocr.ParseLanguages(parseLang)
+		variants, err := oor.DefaultVariants(stages, languages, nil)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		retryBelow, err := oor.RetryBelowFromEnv()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		if cmd.Flags().Changed("retry-below") {
+			retryBelow = parseRetryBelow
+		}
+		corrector, err := ocr.NewCorrector()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
 		parserDeps := oor.ParserDeps{
-			Processor: process.NewDefaultProcessor(),
-			OCR:       ocr.NewTesseractOCR(),
-			TTS:       tts.NewDefaultTTS(),
+			Processor:    processor,
+			OCR:          ocr.NewTesseractOCR(languages),
+			TTS:          tts.NewDefaultTTS(),
+			Selection:    selection,
+			Languages:    languages,
+			SpeakMargins: parseSpeakMargins,
+			Variants:     variants,
+			RetryBelow:   retryBelow,
+			Corrector:    corrector,
+			DebugDir:     oor.DebugDirFromEnv(),
 		}
 
-		if err := oor.Parse(args[0], parserDeps); err != nil {
+		result, err := oor.Parse(args[0], parserDeps)
+		if err != nil {
+			if explanation, ok := process.Explain(err); ok {
+				logger.Error(explanation)
+				return
+			}
 			logger.Error(err.Error())
+			return
+		}
+
+		doc := result.Document(filepath.Base(args[0]))
+		exports := []struct {
+			format, path string
+			write        func(io.Writer) error
+		}{
+			{export.FormatHOCR, parseHOCR, func(w io.Writer) error { return export.Write(w, export.FormatHOCR, doc) }},
+			{export.FormatALTO, parseALTO, func(w io.Writer) error { return export.Write(w, export.FormatALTO, doc) }},
+			{"pdf", parsePDF, result.WritePDF},
+		}
+		for _, e := range exports {
+			if e.path == "" {
+				continue
+			}
+			if err := writeFile(e.path, e.write); err != nil {
+				logger.Error(err.Error())
+				return
+			}
+			logger.Logf("Wrote %s to %s", e.format, e.path)
 		}
 	},
 }
 
+// writeFile creates the file and writes to it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "creating %s", path)
+	}
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
 var serverCmd = &cobra.Command{
 	Use:           "server",
 	Short:         "start the web server",
@@ -57,9 +154,27 @@ var serverCmd = &cobra.Command{
 		logger := logger.New()
 		logger.Log("Starting the server")
 
+		// The tesseract clients are shared by all the requests
+		pool, err := ocr.NewPoolFromEnv()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		defer pool.Close()
+		warmUp := ocr.LanguagesFromEnv()
+		if warmUp == nil {
+			warmUp = []string{"eng"}
+		}
+		if err := pool.WarmUp(warmUp, pool.Stats().Size); err != nil {
+			logger.Errorf("warming up the OCR: %s", err.Error())
+		}
+		expvar.Publish("ocr_pool", expvar.Func(func() interface{} { return pool.Stats() }))
+
 		r := mux.NewRouter()
 		r.HandleFunc("/", controllers.Home)
-		r.HandleFunc("/upload", controllers.ImageUpload).Methods("POST")
+		r.HandleFunc("/upload", controllers.ImageUpload(pool)).Methods("POST")
+		r.HandleFunc("/dictionary", controllers.DictionaryAdd).Methods("POST")
+		r.Handle("/debug/vars", expvar.Handler())
 		r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
 
 		// https://gist.github.com/xcsrz/538e291d12be6ee9a8c7
@@ -76,9 +191,44 @@ var serverCmd = &cobra.Command{
 	},
 }
 
+var dictionaryCmd = &cobra.Command{
+	Use:   "dictionary",
+	Short: "manage the personal dictionary",
+	Long:  `The words of the personal dictionary (e.g. names) are never "corrected" to other words. It is stored in the file set with OOR_PERSONAL_DICT or in "personal.txt" in the wordlist directory.`,
+}
+
+var dictionaryAddCmd = &cobra.Command{
+	Use:           "add <word>...",
+	Short:         "add words to the personal dictionary",
+	SilenceErrors: true,
+	Args:          cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := logger.New()
+		if err := wordlist.AddPersonal(args...); err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		logger.Logf("Added %d words to %s", len(args), wordlist.PersonalPath())
+	},
+}
+
+var parseSelection, parseLang, parseHOCR, parseALTO, parsePDF string
+var parseRetryBelow float64
+var parseSpeakMargins bool
+
 func init() {
+	parseCmd.Flags().StringVar(&parseLang, "lang", os.Getenv("OOR_LANG"), `the tesseract languages of the text (e.g. "eng" or "eng+ell"). The language is detected when empty`)
+	parseCmd.Flags().Float64Var(&parseRetryBelow, "retry-below", 0, "when the mean OCR confidence (0-100) is below this, try other ways to process the image and keep the best (also set with OOR_RETRY_BELOW)")
+	parseCmd.Flags().BoolVar(&parseSpeakMargins, "speak-margins", false, "also read the headers, the footers and the page numbers out loud")
+	parseCmd.Flags().StringVar(&parseHOCR, "hocr", "", "write the text with its layout as hOCR to this file")
+	parseCmd.Flags().StringVar(&parseALTO, "alto", "", "write the text with its layout as ALTO XML to this file")
+	parseCmd.Flags().StringVar(&parsePDF, "pdf", "", "write the pages of this one photo (two for a book spread) with the text as a searchable PDF to this file. Photos are not combined into one PDF")
+	parseCmd.Flags().StringVar(&parseSelection, "selection", "", `the part of the image to read, as a rectangle ("x,y,width,height") or a polygon ("x1,y1 x2,y2 x3,y3 ...")`)
+
 	rootCmd.AddCommand(parseCmd)
 	rootCmd.AddCommand(serverCmd)
+	dictionaryCmd.AddCommand(dictionaryAddCmd)
+	rootCmd.AddCommand(dictionaryCmd)
 }
 
 func main() {
@@ -3,10 +3,15 @@ let imageInput = document.querySelector("#image-upload");
 document.querySelector("#javascriptContent").style.display='block';
 document.querySelector("#nonJavascriptContent").style.display='none';
 
-document.addEventListener('click', function() {
+document.addEventListener('click', function(e) {
+   // Clicking the options doesn't take a photo
+   if (e.target.closest('label,input')) {
+      return;
+   }
    imageInput.click();
 });
 
 imageInput.addEventListener('change', function() {
+   document.querySelector("#speak-margins-field").value = document.querySelector("#speak-margins").checked;
    document.querySelector("#image-form").submit();
 });
@@ -3,22 +3,71 @@ let imageInput = document.querySelector("#image-upload");
 document.querySelector("#javascriptContent").style.display='block';
 document.querySelector("#nonJavascriptContent").style.display='none';
 
-document.addEventListener('click', function() {
-   canvas.getContext('2d').drawImage(video, 0, 0, canvas.width, canvas.height);
+// Dragging over the video selects the part of the page to read. A simple
+// click reads the whole page.
+let selection = null;
+let dragStart = null;
+
+document.addEventListener('click', function(e) {
+   // Clicking the options doesn't take a photo
+   if (e.target.closest('label,input')) {
+      return;
+   }
+   let context = canvas.getContext('2d');
+   context.drawImage(video, 0, 0, canvas.width, canvas.height);
    let image_data_url = canvas.toDataURL('image/jpeg');
 
+   // Show what was selected (after taking the image, to not upload the outline)
+   if (selection) {
+      context.strokeStyle = 'red';
+      context.lineWidth = 3;
+      context.strokeRect(selection.x, selection.y, selection.width, selection.height);
+   }
+
    // data url of the image
    console.log(image_data_url);
-   appendFileAndSubmit(image_data_url);
+   appendFileAndSubmit(image_data_url, selection);
+   selection = null;
 });
 
 imageInput.addEventListener('change', function() {
+   document.querySelector("#speak-margins-field").value = document.querySelector("#speak-margins").checked;
    document.querySelector("#image-form").submit();
 });
 
 let video = document.querySelector("#video");
 let canvas = document.querySelector("#canvas");
 
+video.addEventListener('mousedown', function(e) {
+   dragStart = {x: e.offsetX, y: e.offsetY};
+   selection = null;
+});
+
+video.addEventListener('mouseup', function(e) {
+   if (!dragStart) {
+      return;
+   }
+   // The video may be scaled on the page. The selection is in the
+   // coordinates of the canvas, which is the image that is uploaded.
+   let scaleX = canvas.width / video.clientWidth;
+   let scaleY = canvas.height / video.clientHeight;
+   let x = Math.min(dragStart.x, e.offsetX);
+   let y = Math.min(dragStart.y, e.offsetY);
+   let width = Math.abs(e.offsetX - dragStart.x);
+   let height = Math.abs(e.offsetY - dragStart.y);
+   dragStart = null;
+   if (width < 10 || height < 10) {
+      return; // a click, not a drag
+   }
+
+   selection = {
+      x: Math.round(x * scaleX),
+      y: Math.round(y * scaleY),
+      width: Math.round(width * scaleX),
+      height: Math.round(height * scaleY)
+   };
+});
+
 navigator.mediaDevices.getUserMedia({
    video: true,
    audio: false
@@ -29,7 +78,7 @@ navigator.mediaDevices.getUserMedia({
    console.log("something went wrong while getting access to the camera: " + err);
 });
 
-function appendFileAndSubmit(ImageURL){
+function appendFileAndSubmit(ImageURL, selection){
    // Get the form
    var form = document.getElementById("desktopForm");
 
@@ -46,6 +95,10 @@ function appendFileAndSubmit(ImageURL){
    // Create a FormData and append the file
    var fd = new FormData(form);
    fd.append("image-file", blob);
+   fd.append("speak-margins", document.querySelector("#speak-margins").checked);
+   if (selection) {
+      fd.append("selection", [selection.x, selection.y, selection.width, selection.height].join(","));
+   }
 
    // Submit Form and upload file
    $.ajax({